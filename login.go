@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+)
+
+// callbackResult is what the loopback server hands back to runLogin.
+type callbackResult struct {
+	code   string
+	scopes []string
+	err    error
+}
+
+// runLogin performs the OAuth authorization-code flow against a loopback
+// callback server and saves the resulting token to the token store.
+func runLogin(args []string) {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	port := fs.Int("port", 8089, "Local port for the OAuth callback")
+	scopes := fs.String("scopes", "read,activity:read_all", "Comma-separated scopes to request")
+	storePath := fs.String("token-store", defaultTokenStorePath(), "Token store file")
+	force := fs.Bool("force", false, "Always show Strava's authorization prompt")
+	timeout := fs.Duration("timeout", 5*time.Minute, "How long to wait for the browser callback")
+	fs.Parse(args)
+
+	clientID, clientSecret := os.Getenv("STRAVA_CLIENT_ID"), os.Getenv("STRAVA_CLIENT_SECRET")
+	if clientID == "" || clientSecret == "" {
+		log.Fatal("login requires STRAVA_CLIENT_ID and STRAVA_CLIENT_SECRET")
+	}
+	requested := splitScopes(*scopes)
+
+	// Listen and redirect on the same address: "localhost" may resolve to
+	// ::1 in the browser while the server only listens on IPv4.
+	addr := fmt.Sprintf("127.0.0.1:%d", *port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Error starting callback server: %v", err)
+	}
+	redirectURL := "http://" + addr + "/exchange_token"
+	cfg := strava.OAuthConfig(clientID, clientSecret, redirectURL, requested...)
+	state, err := randomState()
+	if err != nil {
+		log.Fatalf("Error generating state: %v", err)
+	}
+
+	results := make(chan callbackResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/exchange_token", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var res callbackResult
+		switch {
+		case q.Get("state") != state:
+			res.err = errors.New("state mismatch in OAuth callback")
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization failed: %s", q.Get("error"))
+		case q.Get("code") == "":
+			res.err = errors.New("no code in OAuth callback")
+		default:
+			res.code = q.Get("code")
+			res.scopes = splitScopes(q.Get("scope"))
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Authorization received. You can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	fmt.Printf("Open this URL in your browser to authorize access (%s):\n\n%s\n\n", strings.Join(requested, ", "), strava.AuthCodeURL(cfg, state, *force))
+
+	var res callbackResult
+	select {
+	case res = <-results:
+	case <-time.After(*timeout):
+		log.Fatal("Timed out waiting for the OAuth callback")
+	}
+	if res.err != nil {
+		log.Fatalf("Error: %v", res.err)
+	}
+	if missing := missingScopes(requested, res.scopes); len(missing) > 0 {
+		log.Fatalf("Strava did not grant the requested scopes: %s", strings.Join(missing, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	tok, err := cfg.Exchange(ctx, res.code)
+	if err != nil {
+		log.Fatalf("Error exchanging code: %v", err)
+	}
+	athleteID, ok := strava.TokenAthleteID(tok)
+	if !ok {
+		log.Fatal("Token response did not include the athlete")
+	}
+	if err := strava.NewFileTokenStore(*storePath).Save(athleteID, tok); err != nil {
+		log.Fatalf("Error saving token: %v", err)
+	}
+	fmt.Printf("Logged in as athlete %d; token saved to %s\n", athleteID, *storePath)
+}
+
+func defaultTokenStorePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "strava-tokens.json"
+	}
+	return filepath.Join(dir, "strava", "tokens.json")
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func splitScopes(s string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+func missingScopes(requested, granted []string) []string {
+	have := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		have[scope] = true
+	}
+	var missing []string
+	for _, scope := range requested {
+		if !have[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
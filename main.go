@@ -12,6 +12,11 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "login" {
+		runLogin(os.Args[2:])
+		return
+	}
+
 	createActivity := flag.Bool("create-activity", false, "Create a new activity")
 	activityName := flag.String("activity-name", "", "Activity name")
 	activityType := flag.String("activity-type", "", "Activity type (e.g., Ride)")
@@ -31,15 +36,41 @@ func main() {
 		fetchGear       = flag.Bool("gear", false, "Fetch first bike's gear details")
 		athleteID       = flag.Int64("athlete-id", 0, "Fetch public info for this athlete ID (if allowed)")
 		outputJSON      = flag.Bool("json", true, "Output as JSON (default true)")
+		storedAthlete   = flag.Int64("stored-athlete", 0, "Use the token saved by the login command for this athlete ID")
+		tokenStorePath  = flag.String("token-store", defaultTokenStorePath(), "Token store file used with -stored-athlete")
 	)
 	flag.Parse()
 
-	accessToken := os.Getenv("STRAVA_ACCESS_TOKEN")
-	if accessToken == "" {
-		log.Fatal("STRAVA_ACCESS_TOKEN environment variable not set")
+	var client *strava.Client
+	if *storedAthlete != 0 {
+		clientID, clientSecret := os.Getenv("STRAVA_CLIENT_ID"), os.Getenv("STRAVA_CLIENT_SECRET")
+		if clientID == "" || clientSecret == "" {
+			log.Fatal("-stored-athlete requires STRAVA_CLIENT_ID and STRAVA_CLIENT_SECRET")
+		}
+		var err error
+		client, err = strava.NewClientWithStore(clientID, clientSecret, strava.NewFileTokenStore(*tokenStorePath), *storedAthlete)
+		if err != nil {
+			log.Fatalf("Error loading stored token: %v", err)
+		}
+	} else if refreshToken := os.Getenv("STRAVA_REFRESH_TOKEN"); refreshToken != "" {
+		clientID, clientSecret := os.Getenv("STRAVA_CLIENT_ID"), os.Getenv("STRAVA_CLIENT_SECRET")
+		if clientID == "" || clientSecret == "" {
+			log.Fatal("STRAVA_REFRESH_TOKEN requires STRAVA_CLIENT_ID and STRAVA_CLIENT_SECRET")
+		}
+		client = strava.NewRefreshingClient(clientID, clientSecret, refreshToken, func(tok *oauth2.Token) error {
+			if tok.RefreshToken != refreshToken {
+				fmt.Fprintln(os.Stderr, "Strava rotated the refresh token, so STRAVA_REFRESH_TOKEN will stop working. Run `login` and use -stored-athlete to keep the latest token in the token store.")
+			}
+			return nil
+		})
+	} else {
+		accessToken := os.Getenv("STRAVA_ACCESS_TOKEN")
+		if accessToken == "" {
+			log.Fatal("STRAVA_ACCESS_TOKEN environment variable not set")
+		}
+		token := &oauth2.Token{AccessToken: accessToken}
+		client = strava.NewClient(token)
 	}
-	token := &oauth2.Token{AccessToken: accessToken}
-	client := strava.NewClient(token)
 
 	result := make(map[string]interface{})
 
@@ -4,22 +4,32 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 )
 
 // GetAthleteByID fetches public info for a specific athlete ID (if allowed by Strava API and token)
 func (c *Client) GetAthleteByID(athleteID int64) (*Athlete, error) {
-	url := fmt.Sprintf("%s/athletes/%d", stravaAPIBase, athleteID)
-	resp, err := c.HTTPClient.Get(url)
+	return c.GetAthleteByIDCtx(context.Background(), athleteID)
+}
+
+func (c *Client) GetAthleteByIDCtx(ctx context.Context, athleteID int64) (*Athlete, error) {
+	url := fmt.Sprintf("%s/athletes/%d", c.baseURL(), athleteID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var athlete Athlete
 	if err := json.NewDecoder(resp.Body).Decode(&athlete); err != nil {
@@ -29,8 +39,22 @@ func (c *Client) GetAthleteByID(athleteID int64) (*Athlete, error) {
 }
 
 type SummaryClub struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID              int64    `json:"id"`
+	ResourceState   int      `json:"resource_state"`
+	Name            string   `json:"name"`
+	ProfileMedium   string   `json:"profile_medium"`
+	CoverPhoto      string   `json:"cover_photo"`
+	CoverPhotoSmall string   `json:"cover_photo_small"`
+	SportType       string   `json:"sport_type"`
+	ActivityTypes   []string `json:"activity_types"`
+	City            string   `json:"city"`
+	State           string   `json:"state"`
+	Country         string   `json:"country"`
+	Private         bool     `json:"private"`
+	MemberCount     int      `json:"member_count"`
+	Featured        bool     `json:"featured"`
+	Verified        bool     `json:"verified"`
+	URL             string   `json:"url"`
 }
 
 type SummaryGear struct {
@@ -41,9 +65,33 @@ type SummaryGear struct {
 }
 
 type DetailedActivity struct {
-	Name      string
-	SportType string
-	Distance  float64
+	ID             int64   `json:"id"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Type           string  `json:"type"`
+	SportType      string  `json:"sport_type"`
+	StartDate      string  `json:"start_date"`
+	StartDateLocal string  `json:"start_date_local"`
+	ElapsedTime    int     `json:"elapsed_time"`
+	MovingTime     int     `json:"moving_time"`
+	Distance       float64 `json:"distance"`
+	GearID         string  `json:"gear_id"`
+	Commute        bool    `json:"commute"`
+	Trainer        bool    `json:"trainer"`
+	HideFromHome   bool    `json:"hide_from_home"`
+}
+
+// UpdatableActivity holds the fields UpdateActivity can change. Only
+// non-nil fields are sent, so unset fields keep their current value.
+type UpdatableActivity struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	SportType   *string `json:"sport_type,omitempty"`
+	// GearID set to "none" removes the gear from the activity.
+	GearID       *string `json:"gear_id,omitempty"`
+	Commute      *bool   `json:"commute,omitempty"`
+	Trainer      *bool   `json:"trainer,omitempty"`
+	HideFromHome *bool   `json:"hide_from_home,omitempty"`
 }
 
 type DetailedGear struct {
@@ -52,38 +100,396 @@ type DetailedGear struct {
 	ModelName string
 	ID        string
 }
+
+// Route types and sub-types reported in Route.Type and Route.SubType.
+const (
+	RouteTypeRide = 1
+	RouteTypeRun  = 2
+
+	RouteSubTypeRoad     = 1
+	RouteSubTypeMountain = 2
+	RouteSubTypeCross    = 3
+	RouteSubTypeTrail    = 4
+	RouteSubTypeMixed    = 5
+)
+
 type Route struct {
-	Name     string
-	ID       int64
-	Distance float64
+	ID          int64          `json:"id"`
+	IDStr       string         `json:"id_str"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Athlete     SummaryAthlete `json:"athlete"`
+	Distance    float64        `json:"distance"`
+	// ElevationGain is in meters.
+	ElevationGain float64 `json:"elevation_gain"`
+	Type          int     `json:"type"`
+	SubType       int     `json:"sub_type"`
+	Private       bool    `json:"private"`
+	Starred       bool    `json:"starred"`
+	// Timestamp is the creation time in seconds since the epoch.
+	Timestamp int64 `json:"timestamp"`
+	// EstimatedMovingTime is in seconds.
+	EstimatedMovingTime int               `json:"estimated_moving_time"`
+	Segments            []DetailedSegment `json:"segments"`
+	Map                 PolylineMap       `json:"map"`
+	CreatedAt           string            `json:"created_at"`
+	UpdatedAt           string            `json:"updated_at"`
 }
 type Upload struct {
-	IDStr  string
-	ID     int64
-	Status string
+	ID         int64  `json:"id"`
+	IDStr      string `json:"id_str"`
+	ExternalID string `json:"external_id"`
+	// Error is set when processing failed, e.g. for a duplicate activity.
+	Error  string `json:"error"`
+	Status string `json:"status"`
+	// ActivityID is zero until the upload has been processed.
+	ActivityID int64 `json:"activity_id"`
+}
+type DetailedSegmentEffort struct {
+	ID               int64           `json:"id"`
+	ResourceState    int             `json:"resource_state"`
+	Name             string          `json:"name"`
+	ActivityID       int64           `json:"activity_id"`
+	Activity         MetaActivity    `json:"activity"`
+	Athlete          MetaAthlete     `json:"athlete"`
+	ElapsedTime      int             `json:"elapsed_time"`
+	MovingTime       int             `json:"moving_time"`
+	StartDate        string          `json:"start_date"`
+	StartDateLocal   string          `json:"start_date_local"`
+	Distance         float64         `json:"distance"`
+	StartIndex       int             `json:"start_index"`
+	EndIndex         int             `json:"end_index"`
+	AverageCadence   float64         `json:"average_cadence"`
+	AverageWatts     float64         `json:"average_watts"`
+	DeviceWatts      bool            `json:"device_watts"`
+	AverageHeartrate float64         `json:"average_heartrate"`
+	MaxHeartrate     float64         `json:"max_heartrate"`
+	Segment          DetailedSegment `json:"segment"`
+	KOMRank          *int            `json:"kom_rank"`
+	PRRank           *int            `json:"pr_rank"`
+	IsKOM            bool            `json:"is_kom"`
+	Hidden           bool            `json:"hidden"`
+	Achievements     []Achievement   `json:"achievements"`
+}
+
+type Achievement struct {
+	TypeID int    `json:"type_id"`
+	Type   string `json:"type"`
+	Rank   int    `json:"rank"`
+}
+
+// Zone types reported in ActivityZone.Type.
+const (
+	ZoneTypeHeartrate = "heartrate"
+	ZoneTypePower     = "power"
+)
+
+// ActivityZone is the time an activity spent in each heart-rate or power
+// zone.
+type ActivityZone struct {
+	Type                string                `json:"type"`
+	Score               int                   `json:"score"`
+	Points              int                   `json:"points"`
+	SensorBased         bool                  `json:"sensor_based"`
+	CustomZones         bool                  `json:"custom_zones"`
+	Max                 int                   `json:"max"`
+	DistributionBuckets TimedZoneDistribution `json:"distribution_buckets"`
 }
-type StreamSet struct{}
-type DetailedSegmentEffort struct{}
-type ActivityZone struct{}
 
-type ExplorerResponse struct {
-	Segments []Segment
+// TimedZoneRange is the time in seconds spent between Min and Max. Max is
+// -1 for the open-ended top zone.
+type TimedZoneRange struct {
+	Min  int `json:"min"`
+	Max  int `json:"max"`
+	Time int `json:"time"`
 }
-type Segment struct {
-	Name         string
-	ID           int64
-	Distance     float64
-	AverageGrade float64
+
+type TimedZoneDistribution []TimedZoneRange
+
+type ExplorerResponse struct {
+	Segments []ExplorerSegment `json:"segments"`
+}
+
+type ExplorerSegment struct {
+	ID                int64   `json:"id"`
+	Name              string  `json:"name"`
+	ClimbCategory     int     `json:"climb_category"`
+	ClimbCategoryDesc string  `json:"climb_category_desc"`
+	AverageGrade      float64 `json:"avg_grade"`
+	StartLatLng       LatLng  `json:"start_latlng"`
+	EndLatLng         LatLng  `json:"end_latlng"`
+	ElevDifference    float64 `json:"elev_difference"`
+	Distance          float64 `json:"distance"`
+	Points            string  `json:"points"`
+	Starred           bool    `json:"starred"`
+}
+
+type DetailedSegment struct {
+	ID                  int64                  `json:"id"`
+	ResourceState       int                    `json:"resource_state"`
+	Name                string                 `json:"name"`
+	ActivityType        string                 `json:"activity_type"`
+	Distance            float64                `json:"distance"`
+	AverageGrade        float64                `json:"average_grade"`
+	MaximumGrade        float64                `json:"maximum_grade"`
+	ElevationHigh       float64                `json:"elevation_high"`
+	ElevationLow        float64                `json:"elevation_low"`
+	StartLatLng         LatLng                 `json:"start_latlng"`
+	EndLatLng           LatLng                 `json:"end_latlng"`
+	ClimbCategory       int                    `json:"climb_category"`
+	City                string                 `json:"city"`
+	State               string                 `json:"state"`
+	Country             string                 `json:"country"`
+	Private             bool                   `json:"private"`
+	Hazardous           bool                   `json:"hazardous"`
+	Starred             bool                   `json:"starred"`
+	CreatedAt           string                 `json:"created_at"`
+	UpdatedAt           string                 `json:"updated_at"`
+	TotalElevationGain  float64                `json:"total_elevation_gain"`
+	Map                 PolylineMap            `json:"map"`
+	EffortCount         int                    `json:"effort_count"`
+	AthleteCount        int                    `json:"athlete_count"`
+	StarCount           int                    `json:"star_count"`
+	AthletePREffort     SummaryPRSegmentEffort `json:"athlete_pr_effort"`
+	AthleteSegmentStats SummarySegmentEffort   `json:"athlete_segment_stats"`
+}
+
+type SummaryPRSegmentEffort struct {
+	PRActivityID  int64  `json:"pr_activity_id"`
+	PRElapsedTime int    `json:"pr_elapsed_time"`
+	PRDate        string `json:"pr_date"`
+	EffortCount   int    `json:"effort_count"`
+}
+
+type SummarySegmentEffort struct {
+	ID             int64   `json:"id"`
+	ActivityID     int64   `json:"activity_id"`
+	ElapsedTime    int     `json:"elapsed_time"`
+	StartDate      string  `json:"start_date"`
+	StartDateLocal string  `json:"start_date_local"`
+	Distance       float64 `json:"distance"`
+	IsKOM          bool    `json:"is_kom"`
 }
 
+// ExploreSegments returns the top segments within bounds, given as
+// [south-west lat, south-west lng, north-east lat, north-east lng].
+// activityType is "riding" or "running"; empty means riding. minCat and
+// maxCat filter on climb category (0-5) and are ignored when zero.
 func (c *Client) ExploreSegments(bounds [4]float64, activityType string, minCat, maxCat int) (*ExplorerResponse, error) {
-	return &ExplorerResponse{Segments: []Segment{{Name: "Stub Segment", ID: 1, Distance: 1000, AverageGrade: 5.0}}}, nil
+	return c.ExploreSegmentsCtx(context.Background(), bounds, activityType, minCat, maxCat)
+}
+
+func (c *Client) ExploreSegmentsCtx(ctx context.Context, bounds [4]float64, activityType string, minCat, maxCat int) (*ExplorerResponse, error) {
+	if err := validateBounds(bounds); err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("bounds", fmt.Sprintf("%f,%f,%f,%f", bounds[0], bounds[1], bounds[2], bounds[3]))
+	if activityType != "" {
+		q.Set("activity_type", activityType)
+	}
+	if minCat > 0 {
+		q.Set("min_cat", strconv.Itoa(minCat))
+	}
+	if maxCat > 0 {
+		q.Set("max_cat", strconv.Itoa(maxCat))
+	}
+	url := c.baseURL() + "/segments/explore?" + q.Encode()
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var explorer ExplorerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&explorer); err != nil {
+		return nil, err
+	}
+	return &explorer, nil
+}
+
+func validateBounds(bounds [4]float64) error {
+	swLat, swLng, neLat, neLng := bounds[0], bounds[1], bounds[2], bounds[3]
+	if swLat < -90 || swLat > 90 || neLat < -90 || neLat > 90 || swLng < -180 || swLng > 180 || neLng < -180 || neLng > 180 {
+		return fmt.Errorf("strava: bounds %v out of range", bounds)
+	}
+	if swLat >= neLat || swLng >= neLng {
+		return fmt.Errorf("strava: bounds %v must be ordered [sw_lat, sw_lng, ne_lat, ne_lng]", bounds)
+	}
+	return nil
+}
+
+func (c *Client) GetSegment(id int64) (*DetailedSegment, error) {
+	return c.GetSegmentCtx(context.Background(), id)
+}
+
+func (c *Client) GetSegmentCtx(ctx context.Context, id int64) (*DetailedSegment, error) {
+	url := fmt.Sprintf("%s/segments/%d", c.baseURL(), id)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var segment DetailedSegment
+	if err := json.NewDecoder(resp.Body).Decode(&segment); err != nil {
+		return nil, err
+	}
+	return &segment, nil
+}
+
+// ListStarredSegments lists the authenticated athlete's starred segments.
+// Strava returns summary representations here, so detail-only fields such
+// as Map, EffortCount and AthleteCount are left empty.
+func (c *Client) ListStarredSegments(page, perPage int) ([]DetailedSegment, error) {
+	return c.ListStarredSegmentsCtx(context.Background(), page, perPage)
+}
+
+func (c *Client) ListStarredSegmentsCtx(ctx context.Context, page, perPage int) ([]DetailedSegment, error) {
+	url := fmt.Sprintf("%s/segments/starred?page=%d&per_page=%d", c.baseURL(), page, perPage)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var segments []DetailedSegment
+	if err := json.NewDecoder(resp.Body).Decode(&segments); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+func (c *Client) StarSegment(id int64, starred bool) (*DetailedSegment, error) {
+	return c.StarSegmentCtx(context.Background(), id, starred)
+}
+
+func (c *Client) StarSegmentCtx(ctx context.Context, id int64, starred bool) (*DetailedSegment, error) {
+	url := fmt.Sprintf("%s/segments/%d/starred", c.baseURL(), id)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, strings.NewReader(fmt.Sprintf("starred=%t", starred)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var segment DetailedSegment
+	if err := json.NewDecoder(resp.Body).Decode(&segment); err != nil {
+		return nil, err
+	}
+	return &segment, nil
+}
+
+// SyncStarredSegments stars and unstars segments so that the athlete's
+// starred set matches ids exactly. It returns the IDs it starred and
+// unstarred; on error, the changes made so far are still returned.
+func (c *Client) SyncStarredSegments(ids []int64) (starred, unstarred []int64, err error) {
+	return c.SyncStarredSegmentsCtx(context.Background(), ids)
+}
+
+func (c *Client) SyncStarredSegmentsCtx(ctx context.Context, ids []int64) (starred, unstarred []int64, err error) {
+	var currentIDs []int64
+	current := make(map[int64]bool)
+	for page := 1; ; page++ {
+		segments, err := c.ListStarredSegmentsCtx(ctx, page, maxPerPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, s := range segments {
+			currentIDs = append(currentIDs, s.ID)
+			current[s.ID] = true
+		}
+		if len(segments) < maxPerPage {
+			break
+		}
+	}
+	want := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if want[id] {
+			continue
+		}
+		want[id] = true
+		if current[id] {
+			continue
+		}
+		if _, err := c.StarSegmentCtx(ctx, id, true); err != nil {
+			return starred, unstarred, err
+		}
+		starred = append(starred, id)
+	}
+	for _, id := range currentIDs {
+		if want[id] {
+			continue
+		}
+		if _, err := c.StarSegmentCtx(ctx, id, false); err != nil {
+			return starred, unstarred, err
+		}
+		unstarred = append(unstarred, id)
+	}
+	return starred, unstarred, nil
 }
-func (c *Client) GetSegment(id int64) (*Segment, error) {
-	return &Segment{Name: "Stub Segment", ID: id, Distance: 1000, AverageGrade: 5.0}, nil
+
+// ListSegmentEfforts lists the authenticated athlete's efforts on a
+// segment, optionally limited to those starting between startDate and
+// endDate (in the athlete's local time). Zero times are not sent.
+func (c *Client) ListSegmentEfforts(segmentID int64, startDate, endDate time.Time, page, perPage int) ([]DetailedSegmentEffort, error) {
+	return c.ListSegmentEffortsCtx(context.Background(), segmentID, startDate, endDate, page, perPage)
+}
+
+func (c *Client) ListSegmentEffortsCtx(ctx context.Context, segmentID int64, startDate, endDate time.Time, page, perPage int) ([]DetailedSegmentEffort, error) {
+	url := fmt.Sprintf("%s/segment_efforts?segment_id=%d&page=%d&per_page=%d", c.baseURL(), segmentID, page, perPage)
+	if !startDate.IsZero() {
+		url += "&start_date_local=" + startDate.Format(localTimeLayout)
+	}
+	if !endDate.IsZero() {
+		url += "&end_date_local=" + endDate.Format(localTimeLayout)
+	}
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var efforts []DetailedSegmentEffort
+	if err := json.NewDecoder(resp.Body).Decode(&efforts); err != nil {
+		return nil, err
+	}
+	return efforts, nil
+}
+
+func (c *Client) GetSegmentEffort(id int64) (*DetailedSegmentEffort, error) {
+	return c.GetSegmentEffortCtx(context.Background(), id)
 }
-func (c *Client) ListStarredSegments(page, perPage int) ([]Segment, error) {
-	return []Segment{{Name: "Starred Segment", ID: 2, Distance: 2000, AverageGrade: 3.0}}, nil
+
+func (c *Client) GetSegmentEffortCtx(ctx context.Context, id int64) (*DetailedSegmentEffort, error) {
+	url := fmt.Sprintf("%s/segment_efforts/%d", c.baseURL(), id)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var effort DetailedSegmentEffort
+	if err := json.NewDecoder(resp.Body).Decode(&effort); err != nil {
+		return nil, err
+	}
+	return &effort, nil
 }
 
 type ActivityTotal struct {
@@ -111,33 +517,91 @@ type ActivityStats struct {
 
 type ActivityType string
 
-type BaseStream struct{}
+// ClubActivity is an activity in a club's feed. Strava strips it down to
+// the athlete's name and a few totals.
+type ClubActivity struct {
+	Athlete            SummaryAthlete `json:"athlete"`
+	Name               string         `json:"name"`
+	Distance           float64        `json:"distance"`
+	MovingTime         int            `json:"moving_time"`
+	ElapsedTime        int            `json:"elapsed_time"`
+	TotalElevationGain float64        `json:"total_elevation_gain"`
+	Type               string         `json:"type"`
+	SportType          string         `json:"sport_type"`
+	WorkoutType        *int           `json:"workout_type"`
+}
 
-type ClubActivity struct{}
+// ClubAthlete is a club member or admin. Strava only returns the initial
+// of the last name.
+type ClubAthlete struct {
+	ResourceState int    `json:"resource_state"`
+	FirstName     string `json:"firstname"`
+	LastName      string `json:"lastname"`
+	Member        string `json:"member"`
+	Admin         bool   `json:"admin"`
+	Owner         bool   `json:"owner"`
+}
 
-type ClubAthlete struct{}
+type Error struct {
+	Code     string `json:"code"`
+	Field    string `json:"field"`
+	Resource string `json:"resource"`
+}
 
-type Error struct{}
+type Fault struct {
+	Errors  []Error `json:"errors"`
+	Message string  `json:"message"`
+}
 
-type Fault struct{}
+// Zones are the authenticated athlete's heart-rate and power zones.
+// Power is nil unless the athlete has set an FTP.
+type Zones struct {
+	HeartRate *HeartRateZoneRanges `json:"heart_rate"`
+	Power     *PowerZoneRanges     `json:"power"`
+}
 
-type HeartRateZoneRanges struct{}
+type HeartRateZoneRanges struct {
+	// CustomZones is set when the athlete overrode the zones derived from
+	// their maximum heart rate.
+	CustomZones bool        `json:"custom_zones"`
+	Zones       []ZoneRange `json:"zones"`
+}
+
+// ZoneRange bounds one zone. Max is -1 for the open-ended top zone.
+type ZoneRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
 
 type LatLng []float64
 
-type MetaActivity struct{}
+type MetaActivity struct {
+	ID int64 `json:"id"`
+}
 
-type MetaAthlete struct{}
+type MetaAthlete struct {
+	ID int64 `json:"id"`
+}
 
-type MetaClub struct{}
+type MetaClub struct {
+	ID            int64  `json:"id"`
+	ResourceState int    `json:"resource_state"`
+	Name          string `json:"name"`
+}
 
 type PhotosSummary struct{}
 
 type PhotosSummary_primary struct{}
 
-type PolylineMap struct{}
+type PolylineMap struct {
+	ID              string `json:"id"`
+	Polyline        string `json:"polyline"`
+	SummaryPolyline string `json:"summary_polyline"`
+}
 
-type PowerZoneRanges struct{}
+type PowerZoneRanges struct {
+	Zones []ZoneRange `json:"zones"`
+}
 
 type DetailedAthlete struct {
 	ID                    int64         `json:"id"`
@@ -188,14 +652,18 @@ type DetailedClub struct {
 }
 
 func (c *Client) GetAthleteStats(athleteID int64) (*ActivityStats, error) {
-	url := fmt.Sprintf("%s/athletes/%d/stats", stravaAPIBase, athleteID)
-	resp, err := c.HTTPClient.Get(url)
+	return c.GetAthleteStatsCtx(context.Background(), athleteID)
+}
+
+func (c *Client) GetAthleteStatsCtx(ctx context.Context, athleteID int64) (*ActivityStats, error) {
+	url := fmt.Sprintf("%s/athletes/%d/stats", c.baseURL(), athleteID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var stats ActivityStats
 	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
@@ -205,14 +673,18 @@ func (c *Client) GetAthleteStats(athleteID int64) (*ActivityStats, error) {
 }
 
 func (c *Client) GetSummaryGear(gearID string) (*SummaryGear, error) {
-	url := fmt.Sprintf("%s/gear/%s", stravaAPIBase, gearID)
-	resp, err := c.HTTPClient.Get(url)
+	return c.GetSummaryGearCtx(context.Background(), gearID)
+}
+
+func (c *Client) GetSummaryGearCtx(ctx context.Context, gearID string) (*SummaryGear, error) {
+	url := fmt.Sprintf("%s/gear/%s", c.baseURL(), gearID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var gear SummaryGear
 	if err := json.NewDecoder(resp.Body).Decode(&gear); err != nil {
@@ -223,25 +695,128 @@ func (c *Client) GetSummaryGear(gearID string) (*SummaryGear, error) {
 
 const (
 	stravaAPIBase = "https://www.strava.com/api/v3"
+	// maxPerPage is the largest page size Strava accepts.
+	maxPerPage = 200
+	// localTimeLayout formats local date-times the way Strava expects them
+	// in query parameters.
+	localTimeLayout = "2006-01-02T15:04:05Z"
 )
 
 type Client struct {
 	HTTPClient *http.Client
 	Token      *oauth2.Token
+
+	// WaitOnRateLimit makes calls block until the next rate-limit window
+	// once usage reaches RateLimitThreshold, instead of running into 429s.
+	WaitOnRateLimit bool
+	// RateLimitThreshold is the fraction of each limit that may be used
+	// before WaitOnRateLimit kicks in. Zero means the full limit.
+	RateLimitThreshold float64
+	// Retry decides whether failed requests are retried. A nil policy
+	// disables retries.
+	Retry *RetryPolicy
+
+	apiBase   string
+	oauthBase string
+	userAgent string
+	rateLimit rateLimiter
+	// store and athleteID are set by NewClientWithStore so Deauthorize
+	// can purge the stored token.
+	store     TokenStore
+	athleteID int64
+}
+
+func NewClient(token *oauth2.Token, opts ...Option) *Client {
+	return newClient(oauth2.StaticTokenSource(token), token, newOptions(opts))
 }
 
-func NewClient(token *oauth2.Token) *Client {
+func newClient(ts oauth2.TokenSource, token *oauth2.Token, o *options) *Client {
 	return &Client{
-		HTTPClient: oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(token)),
-		Token:      token,
+		HTTPClient: &http.Client{
+			Transport: &oauth2.Transport{Source: ts, Base: o.transport},
+			Timeout:   o.timeout,
+		},
+		Token:     token,
+		Retry:     DefaultRetryPolicy(),
+		apiBase:   o.baseURL,
+		oauthBase: o.oauthBaseURL,
+		userAgent: o.userAgent,
+	}
+}
+
+func (c *Client) baseURL() string {
+	if c.apiBase != "" {
+		return c.apiBase
+	}
+	return stravaAPIBase
+}
+
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
+
+// do sends req, retrying according to c.Retry. Any status of 400 or
+// above is returned as an *APIError with the response body consumed.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	for attempt := 1; ; attempt++ {
+		if c.WaitOnRateLimit {
+			if err := c.rateLimit.wait(req.Context(), c.RateLimitThreshold, isRead(req.Method)); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.HTTPClient.Do(req)
+		if resp != nil {
+			c.rateLimit.update(resp.Header)
+		}
+		delay, retry := c.Retry.next(req, resp, err, attempt)
+		if retry {
+			if resp != nil {
+				io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+				resp.Body.Close()
+			}
+			if req, err = rewindRequest(req); err != nil {
+				return nil, err
+			}
+			if err := sleepCtx(req.Context(), delay); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			// Network errors embed the request URL, which may carry the
+			// client secret.
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) && urlErr.URL == req.URL.String() {
+				urlErr.URL = redactURL(req.URL)
+			}
+			if attempt > 1 {
+				return nil, fmt.Errorf("strava: giving up after %d attempts: %w", attempt, err)
+			}
+			return nil, err
+		}
+		if resp.StatusCode >= 400 {
+			defer resp.Body.Close()
+			apiErr := newAPIError(resp)
+			apiErr.Attempts = attempt
+			return nil, apiErr
+		}
+		return resp, nil
 	}
 }
 
 type Athlete struct {
-	ID        int64  `json:"id"`
-	Username  string `json:"username"`
-	FirstName string `json:"firstname"`
-	LastName  string `json:"lastname"`
+	ID        int64   `json:"id"`
+	Username  string  `json:"username"`
+	FirstName string  `json:"firstname"`
+	LastName  string  `json:"lastname"`
+	Weight    float64 `json:"weight"`
 	Bikes     []DetailedGear
 }
 
@@ -258,6 +833,7 @@ type Comment struct {
 }
 
 type SummaryAthlete struct {
+	ID        int64  `json:"id"`
 	FirstName string `json:"firstname"`
 	LastName  string `json:"lastname"`
 }
@@ -287,17 +863,21 @@ type SummaryActivity struct {
 }
 
 func (c *Client) ListActivityComments(activityID int64, pageSize int, afterCursor string) ([]Comment, error) {
-	url := fmt.Sprintf("%s/activities/%d/comments?page_size=%d", stravaAPIBase, activityID, pageSize)
+	return c.ListActivityCommentsCtx(context.Background(), activityID, pageSize, afterCursor)
+}
+
+func (c *Client) ListActivityCommentsCtx(ctx context.Context, activityID int64, pageSize int, afterCursor string) ([]Comment, error) {
+	url := fmt.Sprintf("%s/activities/%d/comments?page_size=%d", c.baseURL(), activityID, pageSize)
 	if afterCursor != "" {
 		url += "&after_cursor=" + afterCursor
 	}
-	resp, err := c.HTTPClient.Get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var comments []Comment
 	if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
@@ -307,14 +887,18 @@ func (c *Client) ListActivityComments(activityID int64, pageSize int, afterCurso
 }
 
 func (c *Client) ListActivityKudoers(activityID int64, page, perPage int) ([]SummaryAthlete, error) {
-	url := fmt.Sprintf("%s/activities/%d/kudos?page=%d&per_page=%d", stravaAPIBase, activityID, page, perPage)
-	resp, err := c.HTTPClient.Get(url)
+	return c.ListActivityKudoersCtx(context.Background(), activityID, page, perPage)
+}
+
+func (c *Client) ListActivityKudoersCtx(ctx context.Context, activityID int64, page, perPage int) ([]SummaryAthlete, error) {
+	url := fmt.Sprintf("%s/activities/%d/kudos?page=%d&per_page=%d", c.baseURL(), activityID, page, perPage)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var athletes []SummaryAthlete
 	if err := json.NewDecoder(resp.Body).Decode(&athletes); err != nil {
@@ -324,14 +908,18 @@ func (c *Client) ListActivityKudoers(activityID int64, page, perPage int) ([]Sum
 }
 
 func (c *Client) ListActivityLaps(activityID int64) ([]Lap, error) {
-	url := fmt.Sprintf("%s/activities/%d/laps", stravaAPIBase, activityID)
-	resp, err := c.HTTPClient.Get(url)
+	return c.ListActivityLapsCtx(context.Background(), activityID)
+}
+
+func (c *Client) ListActivityLapsCtx(ctx context.Context, activityID int64) ([]Lap, error) {
+	url := fmt.Sprintf("%s/activities/%d/laps", c.baseURL(), activityID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var laps []Lap
 	if err := json.NewDecoder(resp.Body).Decode(&laps); err != nil {
@@ -341,14 +929,18 @@ func (c *Client) ListActivityLaps(activityID int64) ([]Lap, error) {
 }
 
 func (c *Client) ListAthleteActivities(before, after int64, page, perPage int) ([]SummaryActivity, error) {
-	url := fmt.Sprintf("%s/athlete/activities?before=%d&after=%d&page=%d&per_page=%d", stravaAPIBase, before, after, page, perPage)
-	resp, err := c.HTTPClient.Get(url)
+	return c.ListAthleteActivitiesCtx(context.Background(), before, after, page, perPage)
+}
+
+func (c *Client) ListAthleteActivitiesCtx(ctx context.Context, before, after int64, page, perPage int) ([]SummaryActivity, error) {
+	url := fmt.Sprintf("%s/athlete/activities?before=%d&after=%d&page=%d&per_page=%d", c.baseURL(), before, after, page, perPage)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var activities []SummaryActivity
 	if err := json.NewDecoder(resp.Body).Decode(&activities); err != nil {
@@ -358,7 +950,11 @@ func (c *Client) ListAthleteActivities(before, after int64, page, perPage int) (
 }
 
 func (c *Client) CreateActivity(name, activityType, sportType, startDateLocal string, elapsedTime int, description string, distance float64, trainer, commute int) (*DetailedActivity, error) {
-	url := stravaAPIBase + "/activities"
+	return c.CreateActivityCtx(context.Background(), name, activityType, sportType, startDateLocal, elapsedTime, description, distance, trainer, commute)
+}
+
+func (c *Client) CreateActivityCtx(ctx context.Context, name, activityType, sportType, startDateLocal string, elapsedTime int, description string, distance float64, trainer, commute int) (*DetailedActivity, error) {
+	url := c.baseURL() + "/activities"
 	data := make(map[string]interface{})
 	data["name"] = name
 	if activityType != "" {
@@ -383,18 +979,50 @@ func (c *Client) CreateActivity(name, activityType, sportType, startDateLocal st
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
+	}
+	var activity DetailedActivity
+	if err := json.NewDecoder(resp.Body).Decode(&activity); err != nil {
+		return nil, err
+	}
+	return &activity, nil
+}
+
+// UpdateActivity changes the fields set in update on an activity owned by
+// the authenticated athlete. It requires the activity:write scope.
+func (c *Client) UpdateActivity(id int64, update UpdatableActivity) (*DetailedActivity, error) {
+	return c.UpdateActivityCtx(context.Background(), id, update)
+}
+
+func (c *Client) UpdateActivityCtx(ctx context.Context, id int64, update UpdatableActivity) (*DetailedActivity, error) {
+	url := fmt.Sprintf("%s/activities/%d", c.baseURL(), id)
+	body, err := json.Marshal(update)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
 	}
 	var activity DetailedActivity
 	if err := json.NewDecoder(resp.Body).Decode(&activity); err != nil {
@@ -403,18 +1031,45 @@ func (c *Client) CreateActivity(name, activityType, sportType, startDateLocal st
 	return &activity, nil
 }
 
+// GetActivityZones returns the heart-rate and power zone distribution of
+// an activity. Only zones with data are included.
+func (c *Client) GetActivityZones(id int64) ([]ActivityZone, error) {
+	return c.GetActivityZonesCtx(context.Background(), id)
+}
+
+func (c *Client) GetActivityZonesCtx(ctx context.Context, id int64) ([]ActivityZone, error) {
+	url := fmt.Sprintf("%s/activities/%d/zones", c.baseURL(), id)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var zones []ActivityZone
+	if err := json.NewDecoder(resp.Body).Decode(&zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
 func (c *Client) GetActivityByID(id int64, includeAllEfforts bool) (*DetailedActivity, error) {
-	url := fmt.Sprintf("%s/activities/%d", stravaAPIBase, id)
+	return c.GetActivityByIDCtx(context.Background(), id, includeAllEfforts)
+}
+
+func (c *Client) GetActivityByIDCtx(ctx context.Context, id int64, includeAllEfforts bool) (*DetailedActivity, error) {
+	url := fmt.Sprintf("%s/activities/%d", c.baseURL(), id)
 	if includeAllEfforts {
 		url += "?include_all_efforts=true"
 	}
-	resp, err := c.HTTPClient.Get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var activity DetailedActivity
 	if err := json.NewDecoder(resp.Body).Decode(&activity); err != nil {
@@ -424,14 +1079,18 @@ func (c *Client) GetActivityByID(id int64, includeAllEfforts bool) (*DetailedAct
 }
 
 func (c *Client) GetAthlete() (*Athlete, error) {
-	url := fmt.Sprintf("%s/athlete", stravaAPIBase)
-	resp, err := c.HTTPClient.Get(url)
+	return c.GetAthleteCtx(context.Background())
+}
+
+func (c *Client) GetAthleteCtx(ctx context.Context) (*Athlete, error) {
+	url := fmt.Sprintf("%s/athlete", c.baseURL())
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var athlete Athlete
 	if err := json.NewDecoder(resp.Body).Decode(&athlete); err != nil {
@@ -440,15 +1099,71 @@ func (c *Client) GetAthlete() (*Athlete, error) {
 	return &athlete, nil
 }
 
+// GetAthleteZones returns the authenticated athlete's heart-rate and power
+// zones. It requires the profile:read_all scope.
+func (c *Client) GetAthleteZones() (*Zones, error) {
+	return c.GetAthleteZonesCtx(context.Background())
+}
+
+func (c *Client) GetAthleteZonesCtx(ctx context.Context) (*Zones, error) {
+	url := fmt.Sprintf("%s/athlete/zones", c.baseURL())
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var zones Zones
+	if err := json.NewDecoder(resp.Body).Decode(&zones); err != nil {
+		return nil, err
+	}
+	return &zones, nil
+}
+
+// UpdateAthlete sets the authenticated athlete's weight in kilograms. It
+// requires the profile:write scope.
+func (c *Client) UpdateAthlete(weight float64) (*DetailedAthlete, error) {
+	return c.UpdateAthleteCtx(context.Background(), weight)
+}
+
+func (c *Client) UpdateAthleteCtx(ctx context.Context, weight float64) (*DetailedAthlete, error) {
+	url := fmt.Sprintf("%s/athlete", c.baseURL())
+	body := "weight=" + strconv.FormatFloat(weight, 'f', -1, 64)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var athlete DetailedAthlete
+	if err := json.NewDecoder(resp.Body).Decode(&athlete); err != nil {
+		return nil, err
+	}
+	return &athlete, nil
+}
+
 func (c *Client) GetDetailedGear(gearID string) (*DetailedGear, error) {
-	url := fmt.Sprintf("%s/gear/%s", stravaAPIBase, gearID)
-	resp, err := c.HTTPClient.Get(url)
+	return c.GetDetailedGearCtx(context.Background(), gearID)
+}
+
+func (c *Client) GetDetailedGearCtx(ctx context.Context, gearID string) (*DetailedGear, error) {
+	url := fmt.Sprintf("%s/gear/%s", c.baseURL(), gearID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var gear DetailedGear
 	if err := json.NewDecoder(resp.Body).Decode(&gear); err != nil {
@@ -458,14 +1173,18 @@ func (c *Client) GetDetailedGear(gearID string) (*DetailedGear, error) {
 }
 
 func (c *Client) GetClub(clubID int64) (*DetailedClub, error) {
-	url := fmt.Sprintf("%s/clubs/%d", stravaAPIBase, clubID)
-	resp, err := c.HTTPClient.Get(url)
+	return c.GetClubCtx(context.Background(), clubID)
+}
+
+func (c *Client) GetClubCtx(ctx context.Context, clubID int64) (*DetailedClub, error) {
+	url := fmt.Sprintf("%s/clubs/%d", c.baseURL(), clubID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var club DetailedClub
 	if err := json.NewDecoder(resp.Body).Decode(&club); err != nil {
@@ -474,15 +1193,106 @@ func (c *Client) GetClub(clubID int64) (*DetailedClub, error) {
 	return &club, nil
 }
 
+func (c *Client) ListClubMembers(clubID int64, page, perPage int) ([]ClubAthlete, error) {
+	return c.ListClubMembersCtx(context.Background(), clubID, page, perPage)
+}
+
+func (c *Client) ListClubMembersCtx(ctx context.Context, clubID int64, page, perPage int) ([]ClubAthlete, error) {
+	url := fmt.Sprintf("%s/clubs/%d/members?page=%d&per_page=%d", c.baseURL(), clubID, page, perPage)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var members []ClubAthlete
+	if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (c *Client) ListClubAdmins(clubID int64, page, perPage int) ([]ClubAthlete, error) {
+	return c.ListClubAdminsCtx(context.Background(), clubID, page, perPage)
+}
+
+func (c *Client) ListClubAdminsCtx(ctx context.Context, clubID int64, page, perPage int) ([]ClubAthlete, error) {
+	url := fmt.Sprintf("%s/clubs/%d/admins?page=%d&per_page=%d", c.baseURL(), clubID, page, perPage)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var admins []ClubAthlete
+	if err := json.NewDecoder(resp.Body).Decode(&admins); err != nil {
+		return nil, err
+	}
+	return admins, nil
+}
+
+// ListClubActivities returns the club's recent activities, newest first.
+// The authenticated athlete must be a member of the club.
+func (c *Client) ListClubActivities(clubID int64, page, perPage int) ([]ClubActivity, error) {
+	return c.ListClubActivitiesCtx(context.Background(), clubID, page, perPage)
+}
+
+func (c *Client) ListClubActivitiesCtx(ctx context.Context, clubID int64, page, perPage int) ([]ClubActivity, error) {
+	url := fmt.Sprintf("%s/clubs/%d/activities?page=%d&per_page=%d", c.baseURL(), clubID, page, perPage)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var activities []ClubActivity
+	if err := json.NewDecoder(resp.Body).Decode(&activities); err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
+
+// ListAthleteClubs returns the clubs the authenticated athlete belongs to.
+func (c *Client) ListAthleteClubs(page, perPage int) ([]SummaryClub, error) {
+	return c.ListAthleteClubsCtx(context.Background(), page, perPage)
+}
+
+func (c *Client) ListAthleteClubsCtx(ctx context.Context, page, perPage int) ([]SummaryClub, error) {
+	url := fmt.Sprintf("%s/athlete/clubs?page=%d&per_page=%d", c.baseURL(), page, perPage)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var clubs []SummaryClub
+	if err := json.NewDecoder(resp.Body).Decode(&clubs); err != nil {
+		return nil, err
+	}
+	return clubs, nil
+}
+
 func (c *Client) GetRoute(routeID int64) (*Route, error) {
-	url := fmt.Sprintf("%s/routes/%d", stravaAPIBase, routeID)
-	resp, err := c.HTTPClient.Get(url)
+	return c.GetRouteCtx(context.Background(), routeID)
+}
+
+func (c *Client) GetRouteCtx(ctx context.Context, routeID int64) (*Route, error) {
+	url := fmt.Sprintf("%s/routes/%d", c.baseURL(), routeID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var route Route
 	if err := json.NewDecoder(resp.Body).Decode(&route); err != nil {
@@ -491,15 +1301,72 @@ func (c *Client) GetRoute(routeID int64) (*Route, error) {
 	return &route, nil
 }
 
+// ListAthleteRoutes returns the routes created by an athlete. Only the
+// authenticated athlete's private routes are included.
+func (c *Client) ListAthleteRoutes(athleteID int64, page, perPage int) ([]Route, error) {
+	return c.ListAthleteRoutesCtx(context.Background(), athleteID, page, perPage)
+}
+
+func (c *Client) ListAthleteRoutesCtx(ctx context.Context, athleteID int64, page, perPage int) ([]Route, error) {
+	url := fmt.Sprintf("%s/athletes/%d/routes?page=%d&per_page=%d", c.baseURL(), athleteID, page, perPage)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var routes []Route
+	if err := json.NewDecoder(resp.Body).Decode(&routes); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// ExportRouteGPX returns the route as a GPX file. The caller must close it.
+func (c *Client) ExportRouteGPX(routeID int64) (io.ReadCloser, error) {
+	return c.ExportRouteGPXCtx(context.Background(), routeID)
+}
+
+func (c *Client) ExportRouteGPXCtx(ctx context.Context, routeID int64) (io.ReadCloser, error) {
+	return c.exportRoute(ctx, fmt.Sprintf("%s/routes/%d/export_gpx", c.baseURL(), routeID))
+}
+
+// ExportRouteTCX returns the route as a TCX file. The caller must close it.
+func (c *Client) ExportRouteTCX(routeID int64) (io.ReadCloser, error) {
+	return c.ExportRouteTCXCtx(context.Background(), routeID)
+}
+
+func (c *Client) ExportRouteTCXCtx(ctx context.Context, routeID int64) (io.ReadCloser, error) {
+	return c.exportRoute(ctx, fmt.Sprintf("%s/routes/%d/export_tcx", c.baseURL(), routeID))
+}
+
+func (c *Client) exportRoute(ctx context.Context, url string) (io.ReadCloser, error) {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+	return resp.Body, nil
+}
+
 func (c *Client) GetUpload(uploadID int64) (*Upload, error) {
-	url := fmt.Sprintf("%s/uploads/%d", stravaAPIBase, uploadID)
-	resp, err := c.HTTPClient.Get(url)
+	return c.GetUploadCtx(context.Background(), uploadID)
+}
+
+func (c *Client) GetUploadCtx(ctx context.Context, uploadID int64) (*Upload, error) {
+	url := fmt.Sprintf("%s/uploads/%d", c.baseURL(), uploadID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var upload Upload
 	if err := json.NewDecoder(resp.Body).Decode(&upload); err != nil {
@@ -509,14 +1376,53 @@ func (c *Client) GetUpload(uploadID int64) (*Upload, error) {
 }
 
 func (c *Client) GetActivityStreams(activityID int64, keys []string, keyByType bool) (*StreamSet, error) {
-	url := fmt.Sprintf("%s/activities/%d/streams?keys=%s&key_by_type=%t", stravaAPIBase, activityID, joinKeys(keys), keyByType)
-	resp, err := c.HTTPClient.Get(url)
+	return c.GetActivityStreamsCtx(context.Background(), activityID, keys, keyByType)
+}
+
+func (c *Client) GetActivityStreamsCtx(ctx context.Context, activityID int64, keys []string, keyByType bool) (*StreamSet, error) {
+	url := fmt.Sprintf("%s/activities/%d/streams?keys=%s&key_by_type=%t", c.baseURL(), activityID, joinKeys(keys), keyByType)
+	return c.getStreams(ctx, url)
+}
+
+// GetSegmentStreams returns the reference streams of a segment. Strava
+// only serves latlng, distance and altitude for segments.
+func (c *Client) GetSegmentStreams(segmentID int64, keys []string, keyByType bool) (*StreamSet, error) {
+	return c.GetSegmentStreamsCtx(context.Background(), segmentID, keys, keyByType)
+}
+
+func (c *Client) GetSegmentStreamsCtx(ctx context.Context, segmentID int64, keys []string, keyByType bool) (*StreamSet, error) {
+	url := fmt.Sprintf("%s/segments/%d/streams?keys=%s&key_by_type=%t", c.baseURL(), segmentID, joinKeys(keys), keyByType)
+	return c.getStreams(ctx, url)
+}
+
+func (c *Client) GetSegmentEffortStreams(effortID int64, keys []string, keyByType bool) (*StreamSet, error) {
+	return c.GetSegmentEffortStreamsCtx(context.Background(), effortID, keys, keyByType)
+}
+
+func (c *Client) GetSegmentEffortStreamsCtx(ctx context.Context, effortID int64, keys []string, keyByType bool) (*StreamSet, error) {
+	url := fmt.Sprintf("%s/segment_efforts/%d/streams?keys=%s&key_by_type=%t", c.baseURL(), effortID, joinKeys(keys), keyByType)
+	return c.getStreams(ctx, url)
+}
+
+// GetRouteStreams returns a route's streams. The endpoint takes no keys and
+// always answers with every stream the route has.
+func (c *Client) GetRouteStreams(routeID int64) (*StreamSet, error) {
+	return c.GetRouteStreamsCtx(context.Background(), routeID)
+}
+
+func (c *Client) GetRouteStreamsCtx(ctx context.Context, routeID int64) (*StreamSet, error) {
+	url := fmt.Sprintf("%s/routes/%d/streams", c.baseURL(), routeID)
+	return c.getStreams(ctx, url)
+}
+
+func (c *Client) getStreams(ctx context.Context, url string) (*StreamSet, error) {
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		return nil, newAPIError(resp)
 	}
 	var streams StreamSet
 	if err := json.NewDecoder(resp.Body).Decode(&streams); err != nil {
@@ -553,9 +1459,12 @@ type Activity struct {
 	AverageCadence   float64                 `json:"average_cadence"`
 	AverageHeartRate int                     `json:"average_heart_rate"`
 	Temperature      int                     `json:"temperature"`
-	Commute          int                     `json:"commute"`
-	Trainer          int                     `json:"trainer"`
+	Commute          bool                    `json:"commute"`
+	Trainer          bool                    `json:"trainer"`
 	Private          bool                    `json:"private"`
+	Description      string                  `json:"description"`
+	GearID           string                  `json:"gear_id"`
+	HideFromHome     bool                    `json:"hide_from_home"`
 	Starred          bool                    `json:"starred"`
 	CreatedAt        string                  `json:"created_at"`
 	UpdatedAt        string                  `json:"updated_at"`
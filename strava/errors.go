@@ -0,0 +1,122 @@
+package strava
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Sentinel errors matched by APIError.Is, so callers can branch with
+// errors.Is(err, strava.ErrNotFound) and friends.
+var (
+	ErrBadRequest   = errors.New("strava: bad request")
+	ErrUnauthorized = errors.New("strava: unauthorized")
+	ErrForbidden    = errors.New("strava: forbidden")
+	ErrNotFound     = errors.New("strava: not found")
+	ErrRateLimited  = errors.New("strava: rate limit exceeded")
+	ErrServerError  = errors.New("strava: server error")
+
+	// ErrSubscriptionExists matches the error Strava returns when the
+	// application already has a push subscription.
+	ErrSubscriptionExists = errors.New("strava: push subscription already exists")
+)
+
+// maxErrorBody bounds how much of an error response is read.
+const maxErrorBody = 1 << 20
+
+// APIError is returned when Strava answers with an unexpected status. It
+// carries the decoded Fault body along with the request details.
+type APIError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        string
+	RateLimit  RateLimitStatus
+	Fault      Fault
+	// Attempts is how many times the request was sent, including retries.
+	Attempts int
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "strava: %s %s: %s", e.Method, e.URL, e.Status)
+	if e.Fault.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Fault.Message)
+	}
+	for _, fe := range e.Fault.Errors {
+		fmt.Fprintf(&b, " (resource=%s field=%s code=%s)", fe.Resource, fe.Field, fe.Code)
+	}
+	if e.Attempts > 1 {
+		fmt.Fprintf(&b, " after %d attempts", e.Attempts)
+	}
+	return b.String()
+}
+
+// Is reports whether target is the sentinel matching e's status code.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServerError:
+		return e.StatusCode >= 500
+	case ErrSubscriptionExists:
+		for _, fe := range e.Fault.Errors {
+			if fe.Resource == "PushSubscription" && fe.Code == "already exists" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newAPIError builds an APIError from resp, consuming its body.
+func newAPIError(resp *http.Response) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Attempts:   1,
+	}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.URL = redactURL(resp.Request.URL)
+	}
+	e.RateLimit, _ = parseRateLimit(resp.Header)
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err := json.Unmarshal(body, &e.Fault); err != nil {
+		e.Fault = Fault{Message: strings.TrimSpace(string(body))}
+	}
+	return e
+}
+
+// redactedParams are query parameters kept out of error messages.
+var redactedParams = []string{"client_secret", "access_token", "refresh_token"}
+
+// redactURL returns u as a string with secret query parameters masked.
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	redacted := false
+	for _, k := range redactedParams {
+		if q.Has(k) {
+			q.Set(k, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
@@ -0,0 +1,163 @@
+package strava
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	AuthURL        = "https://www.strava.com/oauth/authorize"
+	TokenURL       = "https://www.strava.com/oauth/token"
+	DeauthorizeURL = "https://www.strava.com/oauth/deauthorize"
+)
+
+// Endpoint is Strava's OAuth2 endpoint. Strava expects the client
+// credentials as form parameters rather than basic auth.
+var Endpoint = oauth2.Endpoint{
+	AuthURL:   AuthURL,
+	TokenURL:  TokenURL,
+	AuthStyle: oauth2.AuthStyleInParams,
+}
+
+// Scopes understood by Strava's authorize endpoint.
+const (
+	ScopeRead            = "read"
+	ScopeReadAll         = "read_all"
+	ScopeProfileReadAll  = "profile:read_all"
+	ScopeProfileWrite    = "profile:write"
+	ScopeActivityRead    = "activity:read"
+	ScopeActivityReadAll = "activity:read_all"
+	ScopeActivityWrite   = "activity:write"
+)
+
+// OAuthConfig returns an oauth2.Config for Strava. Strava wants scopes as a
+// single comma-separated value, so they are joined here rather than left
+// to the oauth2 package, which would separate them with spaces.
+func OAuthConfig(clientID, clientSecret, redirectURL string, scopes ...string) *oauth2.Config {
+	cfg := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     Endpoint,
+		RedirectURL:  redirectURL,
+	}
+	if len(scopes) > 0 {
+		cfg.Scopes = []string{strings.Join(scopes, ",")}
+	}
+	return cfg
+}
+
+// AuthCodeURL returns the URL to send the athlete to. With force set,
+// Strava shows the authorization prompt even if the athlete has already
+// granted access (approval_prompt=force).
+func AuthCodeURL(cfg *oauth2.Config, state string, force bool) string {
+	prompt := "auto"
+	if force {
+		prompt = "force"
+	}
+	return cfg.AuthCodeURL(state, oauth2.SetAuthURLParam("approval_prompt", prompt))
+}
+
+// TokenAthleteID returns the athlete ID Strava includes in the token
+// response of an authorization-code exchange.
+func TokenAthleteID(tok *oauth2.Token) (int64, bool) {
+	athlete, ok := tok.Extra("athlete").(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	id, ok := athlete["id"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int64(id), true
+}
+
+// TokenNotifyFunc is called whenever a refreshing client mints a new
+// token. Strava rotates refresh tokens, so the new token's RefreshToken
+// must be persisted or the athlete will have to authorize again.
+type TokenNotifyFunc func(tok *oauth2.Token) error
+
+// NewRefreshingClient returns a Client that exchanges refreshToken for an
+// access token on first use and refreshes it whenever it expires. onToken,
+// if non-nil, is called with every new token.
+func NewRefreshingClient(clientID, clientSecret, refreshToken string, onToken TokenNotifyFunc, opts ...Option) *Client {
+	return NewClientFromToken(clientID, clientSecret, &oauth2.Token{RefreshToken: refreshToken}, onToken, opts...)
+}
+
+// NewClientFromToken is like NewRefreshingClient but starts from a full
+// token, such as one loaded from storage, so a still-valid access token is
+// used until it expires.
+func NewClientFromToken(clientID, clientSecret string, token *oauth2.Token, onToken TokenNotifyFunc, opts ...Option) *Client {
+	o := newOptions(opts)
+	cfg := OAuthConfig(clientID, clientSecret, "")
+	cfg.Endpoint = o.endpoint()
+	var ts oauth2.TokenSource = cfg.TokenSource(o.context(), token)
+	if onToken != nil {
+		ts = &notifyingTokenSource{src: ts, last: token, notify: onToken}
+	}
+	return newClient(ts, token, o)
+}
+
+// notifyingTokenSource calls notify each time src hands out a token it has
+// not seen before.
+type notifyingTokenSource struct {
+	src    oauth2.TokenSource
+	notify TokenNotifyFunc
+
+	mu   sync.Mutex
+	last *oauth2.Token
+}
+
+func (s *notifyingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last != nil && s.last.AccessToken == tok.AccessToken && s.last.RefreshToken == tok.RefreshToken {
+		return tok, nil
+	}
+	// Only remember the token once notify succeeds, so a failed save is
+	// retried on the next call instead of being silently dropped.
+	if err := s.notify(tok); err != nil {
+		return nil, fmt.Errorf("strava: token notify: %w", err)
+	}
+	s.last = tok
+	return tok, nil
+}
+
+// Deauthorize revokes the client's access for the athlete, invalidating
+// all of the application's tokens for them. For a client created with
+// NewClientWithStore, the athlete's token is then removed from the store.
+func (c *Client) Deauthorize(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.deauthorizeURL(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+	if c.store != nil {
+		return c.store.Delete(c.athleteID)
+	}
+	return nil
+}
+
+// deauthorizeURL returns DeauthorizeURL, or its equivalent under the root
+// set by WithOAuthBaseURL.
+func (c *Client) deauthorizeURL() string {
+	if c.oauthBase == "" {
+		return DeauthorizeURL
+	}
+	return c.oauthBase + "/deauthorize"
+}
@@ -0,0 +1,50 @@
+package strava_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+	"github.com/yrludev/strava-golang-api-wrapper/strava/stravatest"
+	"golang.org/x/oauth2"
+)
+
+func TestRefreshingClientAgainstStravatest(t *testing.T) {
+	srv := stravatest.NewServer(&stravatest.State{
+		Athlete:      strava.Athlete{ID: 42},
+		ClientID:     "1",
+		ClientSecret: "secret",
+		RefreshToken: "initial",
+	})
+	defer srv.Close()
+	opts := []strava.Option{strava.WithBaseURL(srv.BaseURL()), strava.WithOAuthBaseURL(srv.OAuthBaseURL())}
+
+	var saved []*oauth2.Token
+	client := strava.NewRefreshingClient("1", "secret", "initial", func(tok *oauth2.Token) error {
+		saved = append(saved, tok)
+		return nil
+	}, opts...)
+	athlete, err := client.GetAthlete()
+	if err != nil {
+		t.Fatalf("GetAthlete: %v", err)
+	}
+	if athlete.ID != 42 {
+		t.Errorf("athlete ID = %d, want 42", athlete.ID)
+	}
+	if len(saved) != 1 || saved[0].RefreshToken == "initial" || saved[0].RefreshToken == "" {
+		t.Fatalf("onToken got %+v, want one token with a rotated refresh token", saved)
+	}
+
+	stale := strava.NewRefreshingClient("1", "secret", "initial", nil, opts...)
+	if _, err := stale.GetAthlete(); err == nil {
+		t.Error("GetAthlete with a rotated-out refresh token succeeded")
+	}
+
+	if err := client.Deauthorize(context.Background()); err != nil {
+		t.Fatalf("Deauthorize: %v", err)
+	}
+	revoked := strava.NewRefreshingClient("1", "secret", saved[0].RefreshToken, nil, opts...)
+	if _, err := revoked.GetAthlete(); err == nil {
+		t.Error("GetAthlete after Deauthorize succeeded")
+	}
+}
@@ -0,0 +1,88 @@
+package strava
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Option configures a Client at construction time.
+type Option func(*options)
+
+type options struct {
+	baseURL      string
+	oauthBaseURL string
+	transport    http.RoundTripper
+	userAgent    string
+	timeout      time.Duration
+}
+
+// WithBaseURL points the client at a different API root, such as a local
+// stand-in server or a proxy. It defaults to https://www.strava.com/api/v3.
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithOAuthBaseURL points token refresh and Deauthorize at a different
+// OAuth root, such as a local stand-in server. It defaults to
+// https://www.strava.com/oauth.
+func WithOAuthBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.oauthBaseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithTransport sets the RoundTripper beneath the OAuth transport. It is
+// also used when refreshing tokens.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = rt
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every API request.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithTimeout sets the overall timeout of each HTTP request.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// endpoint returns Endpoint, with the token URL moved to the root set by
+// WithOAuthBaseURL.
+func (o *options) endpoint() oauth2.Endpoint {
+	ep := Endpoint
+	if o.oauthBaseURL != "" {
+		ep.TokenURL = o.oauthBaseURL + "/token"
+	}
+	return ep
+}
+
+// context returns a context carrying the configured transport, for use by
+// the oauth2 package when it refreshes tokens.
+func (o *options) context() context.Context {
+	ctx := context.Background()
+	if o.transport != nil || o.timeout > 0 {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: o.transport, Timeout: o.timeout})
+	}
+	return ctx
+}
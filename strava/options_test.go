@@ -0,0 +1,33 @@
+package strava_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+)
+
+func TestWithOAuthBaseURL(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/oauth/token" {
+			w.Write([]byte(`{"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	client := strava.NewRefreshingClient("id", "secret", "refresh", nil,
+		strava.WithBaseURL(srv.URL+"/api/v3"), strava.WithOAuthBaseURL(srv.URL+"/oauth/"))
+	if _, err := client.GetAthlete(); err != nil {
+		t.Fatalf("GetAthlete: %v", err)
+	}
+	want := []string{"/oauth/token", "/api/v3/athlete"}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("server saw %v, want %v", paths, want)
+	}
+}
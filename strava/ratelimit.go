@@ -0,0 +1,169 @@
+package strava
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Strava resets the short-term window on each quarter hour and the
+// long-term window at midnight UTC.
+const shortTermWindow = 15 * time.Minute
+
+// RateLimitStatus is a snapshot of Strava's X-RateLimit-Limit and
+// X-RateLimit-Usage headers, and of the X-ReadRateLimit-* pair that
+// applies separately, and more strictly, to read requests. The short-term
+// window is 15 minutes and the long-term window is one day.
+type RateLimitStatus struct {
+	ShortTermLimit     int       `json:"short_term_limit"`
+	LongTermLimit      int       `json:"long_term_limit"`
+	ShortTermUsage     int       `json:"short_term_usage"`
+	LongTermUsage      int       `json:"long_term_usage"`
+	ReadShortTermLimit int       `json:"read_short_term_limit"`
+	ReadLongTermLimit  int       `json:"read_long_term_limit"`
+	ReadShortTermUsage int       `json:"read_short_term_usage"`
+	ReadLongTermUsage  int       `json:"read_long_term_usage"`
+	ObservedAt         time.Time `json:"observed_at"`
+}
+
+// ShortTermReset returns when the 15-minute window containing ObservedAt ends.
+func (s RateLimitStatus) ShortTermReset() time.Time {
+	return s.ObservedAt.UTC().Truncate(shortTermWindow).Add(shortTermWindow)
+}
+
+// LongTermReset returns when the daily window containing ObservedAt ends.
+func (s RateLimitStatus) LongTermReset() time.Time {
+	y, m, d := s.ObservedAt.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// RateLimit returns the rate-limit status reported by the most recent
+// response. Requests sent since then are not counted, even those that
+// WaitOnRateLimit has already reserved. ObservedAt is zero if no response
+// has carried the headers yet.
+func (c *Client) RateLimit() RateLimitStatus {
+	c.rateLimit.mu.Lock()
+	defer c.rateLimit.mu.Unlock()
+	return c.rateLimit.status
+}
+
+// rateLimiter tracks the latest rate-limit headers seen by a Client.
+type rateLimiter struct {
+	mu     sync.Mutex
+	status RateLimitStatus
+	// reserved and readReserved count the requests wait has let through
+	// since status was observed. They are kept apart from status so
+	// RateLimit only reports what Strava sent.
+	reserved     int
+	readReserved int
+}
+
+func (r *rateLimiter) update(h http.Header) {
+	status, ok := parseRateLimit(h)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	r.status = status
+	r.reserved, r.readReserved = 0, 0
+	r.mu.Unlock()
+}
+
+// wait blocks until a request fits under threshold of both limits, and of
+// the read limits too for a read request, then reserves it so concurrent
+// callers do not all slip through the same gap.
+func (r *rateLimiter) wait(ctx context.Context, threshold float64, read bool) error {
+	if threshold <= 0 || threshold > 1 {
+		threshold = 1
+	}
+	for {
+		r.mu.Lock()
+		until := r.blockedUntil(time.Now(), threshold, read)
+		if until.IsZero() {
+			r.reserved++
+			if read {
+				r.readReserved++
+			}
+			r.mu.Unlock()
+			return nil
+		}
+		r.mu.Unlock()
+
+		if err := sleepCtx(ctx, time.Until(until)); err != nil {
+			return err
+		}
+	}
+}
+
+// blockedUntil returns the window reset to wait for, or the zero time if a
+// request may go ahead now. The caller must hold r.mu.
+func (r *rateLimiter) blockedUntil(now time.Time, threshold float64, read bool) time.Time {
+	s := r.status
+	if s.ObservedAt.IsZero() {
+		return time.Time{}
+	}
+	if reset := s.LongTermReset(); now.Before(reset) {
+		if exceeds(s.LongTermUsage+r.reserved, s.LongTermLimit, threshold) ||
+			(read && exceeds(s.ReadLongTermUsage+r.readReserved, s.ReadLongTermLimit, threshold)) {
+			return reset
+		}
+	}
+	if reset := s.ShortTermReset(); now.Before(reset) {
+		if exceeds(s.ShortTermUsage+r.reserved, s.ShortTermLimit, threshold) ||
+			(read && exceeds(s.ReadShortTermUsage+r.readReserved, s.ReadShortTermLimit, threshold)) {
+			return reset
+		}
+	}
+	return time.Time{}
+}
+
+func exceeds(usage, limit int, threshold float64) bool {
+	return limit > 0 && float64(usage+1) > threshold*float64(limit)
+}
+
+// parseRateLimit reads the rate-limit headers from h. The boolean is false
+// when the response carried no rate-limit information.
+func parseRateLimit(h http.Header) (RateLimitStatus, bool) {
+	shortLimit, longLimit, okLimit := parseRatePair(h.Get("X-RateLimit-Limit"))
+	shortUsage, longUsage, okUsage := parseRatePair(h.Get("X-RateLimit-Usage"))
+	readShortLimit, readLongLimit, okReadLimit := parseRatePair(h.Get("X-ReadRateLimit-Limit"))
+	readShortUsage, readLongUsage, okReadUsage := parseRatePair(h.Get("X-ReadRateLimit-Usage"))
+	if !okLimit && !okUsage && !okReadLimit && !okReadUsage {
+		return RateLimitStatus{}, false
+	}
+	return RateLimitStatus{
+		ShortTermLimit:     shortLimit,
+		LongTermLimit:      longLimit,
+		ShortTermUsage:     shortUsage,
+		LongTermUsage:      longUsage,
+		ReadShortTermLimit: readShortLimit,
+		ReadLongTermLimit:  readLongLimit,
+		ReadShortTermUsage: readShortUsage,
+		ReadLongTermUsage:  readLongUsage,
+		ObservedAt:         time.Now(),
+	}, true
+}
+
+// isRead reports whether a request counts against the read rate limit.
+func isRead(method string) bool {
+	return method == "GET" || method == "HEAD"
+}
+
+func parseRatePair(v string) (int, int, bool) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	short, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, false
+	}
+	long, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, false
+	}
+	return short, long, true
+}
@@ -0,0 +1,117 @@
+package strava_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+	"github.com/yrludev/strava-golang-api-wrapper/strava/stravatest"
+)
+
+func newRateLimitServer(t *testing.T, rl stravatest.RateLimit) (*stravatest.Server, *strava.Client) {
+	t.Helper()
+	srv := stravatest.NewServer(nil)
+	t.Cleanup(srv.Close)
+	srv.SetRateLimit(rl)
+	client := srv.Client()
+	client.WaitOnRateLimit = true
+	return srv, client
+}
+
+// blocked reports whether call is still waiting after a short while,
+// checking that it gives up with the context's error.
+func blocked(t *testing.T, call func(ctx context.Context) error) bool {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := call(ctx)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return err != nil
+}
+
+func TestWaitOnRateLimitThreshold(t *testing.T) {
+	srv, client := newRateLimitServer(t, stravatest.RateLimit{ShortTermLimit: 10, LongTermLimit: 1000, ShortTermUsage: 7})
+	client.RateLimitThreshold = 0.9
+
+	getAthlete := func(ctx context.Context) error {
+		_, err := client.GetAthleteCtx(ctx)
+		return err
+	}
+	if blocked(t, getAthlete) {
+		t.Fatal("first request blocked below the threshold")
+	}
+	if blocked(t, getAthlete) {
+		t.Fatal("second request blocked below the threshold")
+	}
+	if !blocked(t, getAthlete) {
+		t.Fatal("request went ahead at 9 of 10 with a 0.9 threshold")
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("server saw %d requests, want 2", n)
+	}
+	if got := client.RateLimit().ShortTermUsage; got != 9 {
+		t.Errorf("RateLimit().ShortTermUsage = %d, want the reported 9", got)
+	}
+}
+
+func TestWaitOnReadRateLimit(t *testing.T) {
+	srv, client := newRateLimitServer(t, stravatest.RateLimit{
+		ShortTermLimit:     100,
+		LongTermLimit:      1000,
+		ReadShortTermLimit: 10,
+		ReadLongTermLimit:  100,
+		ReadShortTermUsage: 9,
+	})
+
+	getAthlete := func(ctx context.Context) error {
+		_, err := client.GetAthleteCtx(ctx)
+		return err
+	}
+	if blocked(t, getAthlete) {
+		t.Fatal("first read blocked below the read limit")
+	}
+	if !blocked(t, getAthlete) {
+		t.Fatal("read went ahead with the read limit used up")
+	}
+	createActivity := func(ctx context.Context) error {
+		_, err := client.CreateActivityCtx(ctx, "Lunch Ride", "Ride", "Ride", "2025-08-12T07:00:00Z", 3600, "", 0, 0, 0)
+		return err
+	}
+	if blocked(t, createActivity) {
+		t.Fatal("write blocked by the read limit")
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("server saw %d requests, want 2", n)
+	}
+}
+
+func TestWaitOnRateLimitCancel(t *testing.T) {
+	srv, client := newRateLimitServer(t, stravatest.RateLimit{ShortTermLimit: 10, LongTermLimit: 1000, ShortTermUsage: 9})
+	if _, err := client.GetAthlete(); err != nil {
+		t.Fatalf("GetAthlete: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.GetAthleteCtx(ctx)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("GetAthleteCtx error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetAthleteCtx still waiting after cancel")
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server saw %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,74 @@
+package replay_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+	"github.com/yrludev/strava-golang-api-wrapper/strava/replay"
+	"github.com/yrludev/strava-golang-api-wrapper/strava/stravatest"
+	"golang.org/x/oauth2"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "athlete.json")
+	srv := stravatest.NewServer(&stravatest.State{Athlete: strava.Athlete{ID: 42, FirstName: "Ada"}})
+	baseURL, oauthBaseURL := srv.BaseURL(), srv.OAuthBaseURL()
+
+	rec, err := replay.New(cassette, replay.ModeRecord)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	client := srv.Client(strava.WithTransport(rec))
+	if _, err := client.GetAthlete(); err != nil {
+		t.Fatalf("recording GetAthlete: %v", err)
+	}
+	// stravatest echoes the access token in the deauthorize response.
+	if err := client.Deauthorize(context.Background()); err != nil {
+		t.Fatalf("recording Deauthorize: %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	srv.Close()
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"Authorization", "stravatest"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+
+	rep, err := replay.New(cassette, replay.ModeReplay)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	client = strava.NewClient(&oauth2.Token{AccessToken: "replayed"}, strava.WithBaseURL(baseURL), strava.WithOAuthBaseURL(oauthBaseURL), strava.WithTransport(rep))
+	athlete, err := client.GetAthlete()
+	if err != nil {
+		t.Fatalf("replaying GetAthlete: %v", err)
+	}
+	if athlete.ID != 42 || athlete.FirstName != "Ada" {
+		t.Errorf("replayed athlete = %+v, want ID 42 named Ada", athlete)
+	}
+	if err := client.Deauthorize(context.Background()); err != nil {
+		t.Fatalf("replaying Deauthorize: %v", err)
+	}
+	if unused := rep.Unused(); len(unused) != 0 {
+		t.Errorf("%d interactions were not replayed", len(unused))
+	}
+
+	if _, err := client.GetAthlete(); !errors.Is(err, replay.ErrUnmatched) {
+		t.Errorf("second GetAthlete error = %v, want replay.ErrUnmatched", err)
+	}
+	if _, err := client.GetActivityByID(1, false); !errors.Is(err, replay.ErrUnmatched) {
+		t.Errorf("unrecorded GetActivityByID error = %v, want replay.ErrUnmatched", err)
+	}
+}
@@ -0,0 +1,179 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how a Client retries transient failures: network
+// errors, 429 Too Many Requests and 5xx responses. GET, HEAD and DELETE
+// requests are retried by default; POST, PUT and PATCH only when
+// RetryNonIdempotent is set, since Strava may already have applied them.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the exponential backoff between
+	// attempts. Each delay is jittered to avoid synchronized retries.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxWait caps how long a Retry-After header or rate-limit window
+	// reset may make the client wait. Longer waits are not retried.
+	MaxWait time.Duration
+	// RetryNonIdempotent enables retries for POST, PUT and PATCH.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns the policy NewClient installs: three attempts
+// for idempotent requests, backing off from half a second, and waiting
+// out at most one 15-minute rate-limit window.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		MaxWait:     shortTermWindow,
+	}
+}
+
+// next reports whether attempt should be followed by another one, and
+// how long to wait before it.
+func (p *RetryPolicy) next(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if !p.RetryNonIdempotent && !isIdempotent(req.Method) {
+		return 0, false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, false
+	}
+	if err != nil {
+		if !isTransient(err) {
+			return 0, false
+		}
+		return p.backoff(attempt), true
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+	default:
+		return 0, false
+	}
+	delay := p.backoff(attempt)
+	if wait, ok := serverWait(resp); ok {
+		if p.MaxWait > 0 && wait > p.MaxWait {
+			return 0, false
+		}
+		if wait > delay {
+			delay = wait
+		}
+	}
+	return delay, true
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// serverWait returns how long the server asked us to wait, either through
+// Retry-After or, for a 429, the end of the exhausted rate-limit window.
+func serverWait(resp *http.Response) (time.Duration, bool) {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return time.Until(t), true
+		}
+	}
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	status, ok := parseRateLimit(resp.Header)
+	if !ok {
+		return 0, false
+	}
+	if status.LongTermLimit > 0 && status.LongTermUsage >= status.LongTermLimit {
+		return time.Until(status.LongTermReset()), true
+	}
+	if status.ShortTermLimit > 0 && status.ShortTermUsage >= status.ShortTermLimit {
+		return time.Until(status.ShortTermReset()), true
+	}
+	if resp.Request != nil && isRead(resp.Request.Method) {
+		if status.ReadLongTermLimit > 0 && status.ReadLongTermUsage >= status.ReadLongTermLimit {
+			return time.Until(status.LongTermReset()), true
+		}
+		if status.ReadShortTermLimit > 0 && status.ReadShortTermUsage >= status.ReadShortTermLimit {
+			return time.Until(status.ShortTermReset()), true
+		}
+	}
+	return 0, false
+}
+
+// isTransient reports whether err is a network failure worth retrying, as
+// opposed to a cancellation or an error from a custom transport.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// *url.Error itself satisfies net.Error, so look at what it wraps.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "DELETE":
+		return true
+	}
+	return false
+}
+
+// rewindRequest returns a copy of req with a fresh body for another attempt.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,181 @@
+package strava_test
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+	"github.com/yrludev/strava-golang-api-wrapper/strava/stravatest"
+	"golang.org/x/oauth2"
+)
+
+// fastRetry is DefaultRetryPolicy with backoffs short enough for tests.
+func fastRetry() *strava.RetryPolicy {
+	p := strava.DefaultRetryPolicy()
+	p.MinBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+	return p
+}
+
+func newRetryServer(t *testing.T) (*stravatest.Server, *strava.Client) {
+	t.Helper()
+	srv := stravatest.NewServer(&stravatest.State{Athlete: strava.Athlete{ID: 42}})
+	t.Cleanup(srv.Close)
+	client := srv.Client()
+	client.Retry = fastRetry()
+	return srv, client
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	srv, client := newRetryServer(t)
+	srv.InjectFault(stravatest.Fault{Method: "GET", Path: "/athlete", Status: http.StatusServiceUnavailable, Times: 2})
+
+	athlete, err := client.GetAthlete()
+	if err != nil {
+		t.Fatalf("GetAthlete: %v", err)
+	}
+	if athlete.ID != 42 {
+		t.Errorf("athlete ID = %d, want 42", athlete.ID)
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("server saw %d requests, want 3", n)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv, client := newRetryServer(t)
+	srv.InjectFault(stravatest.Fault{Method: "GET", Path: "/athlete", Status: http.StatusBadGateway})
+
+	_, err := client.GetAthlete()
+	var apiErr *strava.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetAthlete error = %v, want *strava.APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, http.StatusBadGateway)
+	}
+	if apiErr.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", apiErr.Attempts)
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("server saw %d requests, want 3", n)
+	}
+}
+
+func TestRetrySkipsPost(t *testing.T) {
+	srv, client := newRetryServer(t)
+	srv.InjectFault(stravatest.Fault{Method: "POST", Path: "/activities", Status: http.StatusServiceUnavailable, Times: 1})
+
+	_, err := client.CreateActivity("Lunch Ride", "Ride", "Ride", "2025-08-12T07:00:00Z", 3600, "", 0, 0, 0)
+	var apiErr *strava.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreateActivity error = %v, want *strava.APIError", err)
+	}
+	if apiErr.Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1", apiErr.Attempts)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("server saw %d requests, want 1", n)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	srv, client := newRetryServer(t)
+	client.Retry.RetryNonIdempotent = true
+	srv.InjectFault(stravatest.Fault{Method: "POST", Path: "/activities", Status: http.StatusServiceUnavailable, Times: 1})
+
+	if _, err := client.CreateActivity("Lunch Ride", "Ride", "Ride", "2025-08-12T07:00:00Z", 3600, "", 0, 0, 0); err != nil {
+		t.Fatalf("CreateActivity: %v", err)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("server saw %d requests, want 2", n)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	srv, client := newRetryServer(t)
+	srv.InjectFault(stravatest.Fault{
+		Method: "GET",
+		Path:   "/athlete",
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": {"1"}},
+		Times:  1,
+	})
+
+	start := time.Now()
+	if _, err := client.GetAthlete(); err != nil {
+		t.Fatalf("GetAthlete: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("server saw %d requests, want 2", n)
+	}
+}
+
+func TestRetryRateLimitBeyondMaxWait(t *testing.T) {
+	srv, client := newRetryServer(t)
+	client.Retry.MaxWait = time.Millisecond
+	srv.SetRateLimit(stravatest.RateLimit{ShortTermLimit: 100, LongTermLimit: 1000, ShortTermUsage: 100})
+
+	start := time.Now()
+	_, err := client.GetAthlete()
+	if !errors.Is(err, strava.ErrRateLimited) {
+		t.Fatalf("GetAthlete error = %v, want strava.ErrRateLimited", err)
+	}
+	var apiErr *strava.APIError
+	if errors.As(err, &apiErr) && apiErr.Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1: the window reset is beyond MaxWait", apiErr.Attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("gave up after %v, want no wait for the window reset", elapsed)
+	}
+}
+
+// countingTransport counts round trips before handing them to next.
+type countingTransport struct {
+	next  http.RoundTripper
+	count int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.count, 1)
+	return t.next.RoundTrip(req)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRetryTransportErrors(t *testing.T) {
+	srv := stravatest.NewServer(nil)
+	baseURL := srv.BaseURL()
+	srv.Close()
+	errCustom := errors.New("custom transport failure")
+
+	tests := []struct {
+		name  string
+		next  http.RoundTripper
+		tries int32
+	}{
+		{"network error", http.DefaultTransport, 3},
+		{"custom error", roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, errCustom }), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &countingTransport{next: tt.next}
+			client := strava.NewClient(&oauth2.Token{AccessToken: "test"}, strava.WithBaseURL(baseURL), strava.WithTransport(rt))
+			client.Retry = fastRetry()
+			if _, err := client.GetAthlete(); err == nil {
+				t.Fatal("GetAthlete succeeded, want an error")
+			}
+			if n := atomic.LoadInt32(&rt.count); n != tt.tries {
+				t.Errorf("sent %d requests, want %d", n, tt.tries)
+			}
+		})
+	}
+}
@@ -0,0 +1,799 @@
+package stravatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+)
+
+// route dispatches an authenticated API request. parts is the request
+// path below APIPath split on slashes. The caller holds s.mu.
+func (s *Server) route(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case match(r, "GET", parts, "athlete"):
+		writeJSON(w, http.StatusOK, s.state.Athlete)
+	case match(r, "PUT", parts, "athlete"):
+		s.updateAthlete(w, r)
+	case match(r, "GET", parts, "athlete", "zones"):
+		writeJSON(w, http.StatusOK, s.state.AthleteZones)
+	case match(r, "GET", parts, "athlete", "clubs"):
+		s.listAthleteClubs(w, r)
+	case match(r, "GET", parts, "athlete", "activities"):
+		s.listAthleteActivities(w, r)
+	case match(r, "GET", parts, "athletes", "*"):
+		s.getByID(w, parts[1], "Athlete", func(id int64) (interface{}, bool) {
+			a, ok := s.state.Athletes[id]
+			if !ok && id == s.state.Athlete.ID {
+				return s.state.Athlete, true
+			}
+			return a, ok
+		})
+	case match(r, "GET", parts, "athletes", "*", "stats"):
+		s.getByID(w, parts[1], "Athlete", func(id int64) (interface{}, bool) {
+			stats, ok := s.state.Stats[id]
+			return stats, ok
+		})
+	case match(r, "POST", parts, "activities"):
+		s.createActivity(w, r)
+	case match(r, "GET", parts, "activities", "*"):
+		s.getByID(w, parts[1], "Activity", func(id int64) (interface{}, bool) {
+			a, ok := s.state.Activities[id]
+			return a, ok
+		})
+	case match(r, "PUT", parts, "activities", "*"):
+		s.updateActivity(w, r, parts[1])
+	case match(r, "GET", parts, "activities", "*", "laps"):
+		s.getActivityChild(w, parts[1], func(id int64) interface{} {
+			return nonNil(s.state.Laps[id])
+		})
+	case match(r, "GET", parts, "activities", "*", "zones"):
+		s.getActivityChild(w, parts[1], func(id int64) interface{} {
+			return nonNil(s.state.Zones[id])
+		})
+	case match(r, "GET", parts, "activities", "*", "comments"):
+		s.getActivityChild(w, parts[1], func(id int64) interface{} {
+			return pageComments(s.state.Comments[id], r)
+		})
+	case match(r, "GET", parts, "activities", "*", "kudos"):
+		s.getActivityChild(w, parts[1], func(id int64) interface{} {
+			return paginate(s.state.Kudoers[id], r)
+		})
+	case match(r, "GET", parts, "activities", "*", "streams"):
+		s.getByID(w, parts[1], "Activity", func(id int64) (interface{}, bool) {
+			streams, ok := s.state.Streams[id]
+			return streamsResponse(streams, r), ok
+		})
+	case match(r, "GET", parts, "clubs", "*"):
+		s.getByID(w, parts[1], "Club", func(id int64) (interface{}, bool) {
+			club, ok := s.state.Clubs[id]
+			return club, ok
+		})
+	case match(r, "GET", parts, "clubs", "*", "members"):
+		s.getClubChild(w, parts[1], func(id int64) interface{} {
+			return paginate(nonNil(s.state.ClubMembers[id]), r)
+		})
+	case match(r, "GET", parts, "clubs", "*", "admins"):
+		s.getClubChild(w, parts[1], func(id int64) interface{} {
+			admins := []strava.ClubAthlete{}
+			for _, m := range s.state.ClubMembers[id] {
+				if m.Admin {
+					admins = append(admins, m)
+				}
+			}
+			return paginate(admins, r)
+		})
+	case match(r, "GET", parts, "clubs", "*", "activities"):
+		s.getClubChild(w, parts[1], func(id int64) interface{} {
+			return paginate(nonNil(s.state.ClubActivities[id]), r)
+		})
+	case match(r, "GET", parts, "gear", "*"):
+		gear, ok := s.state.Gear[parts[1]]
+		if !ok {
+			notFound(w, "Gear")
+			return
+		}
+		writeJSON(w, http.StatusOK, gear)
+	case match(r, "GET", parts, "routes", "*"):
+		s.getByID(w, parts[1], "Route", func(id int64) (interface{}, bool) {
+			route, ok := s.state.Routes[id]
+			return route, ok
+		})
+	case match(r, "GET", parts, "athletes", "*", "routes"):
+		s.getByID(w, parts[1], "Athlete", func(id int64) (interface{}, bool) {
+			return paginate(s.athleteRoutes(id), r), true
+		})
+	case match(r, "GET", parts, "routes", "*", "export_gpx"):
+		s.exportRoute(w, parts[1], "application/gpx+xml", routeGPX)
+	case match(r, "GET", parts, "routes", "*", "export_tcx"):
+		s.exportRoute(w, parts[1], "application/vnd.garmin.tcx+xml", routeTCX)
+	case match(r, "GET", parts, "routes", "*", "streams"):
+		s.getByID(w, parts[1], "Route", func(id int64) (interface{}, bool) {
+			streams, ok := s.state.RouteStreams[id]
+			return streamsResponse(streams, r), ok
+		})
+	case match(r, "POST", parts, "uploads"):
+		s.createUpload(w, r)
+	case match(r, "GET", parts, "uploads", "*"):
+		s.getByID(w, parts[1], "Upload", func(id int64) (interface{}, bool) {
+			s.processUpload(id)
+			upload, ok := s.state.Uploads[id]
+			return upload, ok
+		})
+	case match(r, "GET", parts, "segments", "explore"):
+		s.exploreSegments(w, r)
+	case match(r, "GET", parts, "segments", "starred"):
+		s.listStarredSegments(w, r)
+	case match(r, "PUT", parts, "segments", "*", "starred"):
+		s.starSegment(w, r, parts[1])
+	case match(r, "GET", parts, "segments", "*", "streams"):
+		s.getByID(w, parts[1], "Segment", func(id int64) (interface{}, bool) {
+			streams, ok := s.state.SegmentStreams[id]
+			return streamsResponse(streams, r), ok
+		})
+	case match(r, "GET", parts, "segments", "*"):
+		s.getByID(w, parts[1], "Segment", func(id int64) (interface{}, bool) {
+			seg, ok := s.state.Segments[id]
+			seg.Starred = s.isStarred(id)
+			return seg, ok
+		})
+	case match(r, "GET", parts, "segment_efforts"):
+		s.listSegmentEfforts(w, r)
+	case match(r, "GET", parts, "segment_efforts", "*"):
+		s.getByID(w, parts[1], "SegmentEffort", func(id int64) (interface{}, bool) {
+			effort, ok := s.state.SegmentEfforts[id]
+			return effort, ok
+		})
+	case match(r, "GET", parts, "segment_efforts", "*", "streams"):
+		s.getByID(w, parts[1], "SegmentEffort", func(id int64) (interface{}, bool) {
+			streams, ok := s.state.SegmentEffortStreams[id]
+			return streamsResponse(streams, r), ok
+		})
+	default:
+		writeFault(w, http.StatusNotFound, "Record Not Found", strava.Error{Resource: "resource", Code: "not found"})
+	}
+}
+
+// match reports whether r has the given method and its path matches
+// pattern, where "*" matches any single segment.
+func match(r *http.Request, method string, parts []string, pattern ...string) bool {
+	if r.Method != method || len(parts) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) getByID(w http.ResponseWriter, rawID, resource string, lookup func(int64) (interface{}, bool)) {
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		badRequest(w, resource, "id")
+		return
+	}
+	v, ok := lookup(id)
+	if !ok {
+		notFound(w, resource)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// getActivityChild serves a list hanging off an existing activity.
+func (s *Server) getActivityChild(w http.ResponseWriter, rawID string, list func(int64) interface{}) {
+	s.getByID(w, rawID, "Activity", func(id int64) (interface{}, bool) {
+		if _, ok := s.state.Activities[id]; !ok {
+			return nil, false
+		}
+		return list(id), true
+	})
+}
+
+// getClubChild serves a list hanging off an existing club.
+func (s *Server) getClubChild(w http.ResponseWriter, rawID string, list func(int64) interface{}) {
+	s.getByID(w, rawID, "Club", func(id int64) (interface{}, bool) {
+		if _, ok := s.state.Clubs[id]; !ok {
+			return nil, false
+		}
+		return list(id), true
+	})
+}
+
+func (s *Server) listAthleteClubs(w http.ResponseWriter, r *http.Request) {
+	clubs := []strava.SummaryClub{}
+	for _, id := range s.state.AthleteClubs {
+		club, ok := s.state.Clubs[id]
+		if !ok {
+			continue
+		}
+		clubs = append(clubs, strava.SummaryClub{
+			ID:              club.ID,
+			ResourceState:   2,
+			Name:            club.Name,
+			ProfileMedium:   club.ProfileMedium,
+			CoverPhoto:      club.CoverPhoto,
+			CoverPhotoSmall: club.CoverPhotoSmall,
+			SportType:       club.SportType,
+			ActivityTypes:   club.ActivityTypes,
+			City:            club.City,
+			State:           club.State,
+			Country:         club.Country,
+			Private:         club.Private,
+			MemberCount:     club.MemberCount,
+			Featured:        club.Featured,
+			Verified:        club.Verified,
+			URL:             club.URL,
+		})
+	}
+	writeJSON(w, http.StatusOK, paginate(clubs, r))
+}
+
+func (s *Server) listAthleteActivities(w http.ResponseWriter, r *http.Request) {
+	before := queryInt(r, "before", 0)
+	after := queryInt(r, "after", 0)
+	var activities []strava.Activity
+	for _, a := range s.state.Activities {
+		if start, err := time.Parse(time.RFC3339, a.StartDate); err == nil {
+			if before > 0 && start.Unix() >= before {
+				continue
+			}
+			if after > 0 && start.Unix() <= after {
+				continue
+			}
+		}
+		activities = append(activities, a)
+	}
+	sort.Slice(activities, func(i, j int) bool {
+		if activities[i].StartDate != activities[j].StartDate {
+			return activities[i].StartDate > activities[j].StartDate
+		}
+		return activities[i].ID > activities[j].ID
+	})
+	writeJSON(w, http.StatusOK, paginate(activities, r))
+}
+
+func (s *Server) updateAthlete(w http.ResponseWriter, r *http.Request) {
+	weight, err := strconv.ParseFloat(r.FormValue("weight"), 64)
+	if err != nil || weight < 0 {
+		badRequest(w, "Athlete", "weight")
+		return
+	}
+	s.state.Athlete.Weight = weight
+	writeJSON(w, http.StatusOK, s.state.Athlete)
+}
+
+func (s *Server) createActivity(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name           string  `json:"name"`
+		Type           string  `json:"type"`
+		SportType      string  `json:"sport_type"`
+		StartDateLocal string  `json:"start_date_local"`
+		ElapsedTime    int     `json:"elapsed_time"`
+		Distance       float64 `json:"distance"`
+		Trainer        int     `json:"trainer"`
+		Commute        int     `json:"commute"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "Activity", "body")
+		return
+	}
+	switch {
+	case body.Name == "":
+		badRequest(w, "Activity", "name")
+		return
+	case body.SportType == "":
+		badRequest(w, "Activity", "sport_type")
+		return
+	case body.StartDateLocal == "":
+		badRequest(w, "Activity", "start_date_local")
+		return
+	case body.ElapsedTime <= 0:
+		badRequest(w, "Activity", "elapsed_time")
+		return
+	}
+	a := strava.Activity{
+		ID:             s.newID(),
+		Name:           body.Name,
+		Type:           body.Type,
+		SportType:      body.SportType,
+		StartDate:      body.StartDateLocal,
+		StartDateLocal: body.StartDateLocal,
+		ElapsedTime:    body.ElapsedTime,
+		MovingTime:     body.ElapsedTime,
+		Distance:       body.Distance,
+		Trainer:        body.Trainer != 0,
+		Commute:        body.Commute != 0,
+		Athlete: strava.SummaryAthlete{
+			ID:        s.state.Athlete.ID,
+			FirstName: s.state.Athlete.FirstName,
+			LastName:  s.state.Athlete.LastName,
+		},
+	}
+	s.state.Activities[a.ID] = a
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (s *Server) updateActivity(w http.ResponseWriter, r *http.Request, rawID string) {
+	var update strava.UpdatableActivity
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		badRequest(w, "Activity", "body")
+		return
+	}
+	if update.Name != nil && *update.Name == "" {
+		badRequest(w, "Activity", "name")
+		return
+	}
+	s.getByID(w, rawID, "Activity", func(id int64) (interface{}, bool) {
+		a, ok := s.state.Activities[id]
+		if !ok {
+			return nil, false
+		}
+		if update.Name != nil {
+			a.Name = *update.Name
+		}
+		if update.Description != nil {
+			a.Description = *update.Description
+		}
+		if update.SportType != nil {
+			a.SportType = *update.SportType
+		}
+		if update.GearID != nil {
+			a.GearID = *update.GearID
+			if a.GearID == "none" {
+				a.GearID = ""
+			}
+		}
+		if update.Commute != nil {
+			a.Commute = *update.Commute
+		}
+		if update.Trainer != nil {
+			a.Trainer = *update.Trainer
+		}
+		if update.HideFromHome != nil {
+			a.HideFromHome = *update.HideFromHome
+		}
+		s.state.Activities[id] = a
+		return a, true
+	})
+}
+
+// Upload statuses reported by Strava.
+const (
+	uploadProcessing = "Your activity is still being processed."
+	uploadReady      = "Your activity is ready."
+	uploadFailed     = "There was an error processing your activity."
+)
+
+// createUpload accepts an activity file. Like Strava, it answers before the
+// file is processed; the activity appears on the next GET of the upload.
+// An empty file fails processing.
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		badRequest(w, "Upload", "body")
+		return
+	}
+	dataType := r.FormValue("data_type")
+	switch strings.TrimSuffix(dataType, ".gz") {
+	case "fit", "tcx", "gpx":
+	default:
+		badRequest(w, "Upload", "data_type")
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		badRequest(w, "Upload", "file")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		badRequest(w, "Upload", "file")
+		return
+	}
+	upload := strava.Upload{
+		ID:         s.newID(),
+		ExternalID: r.FormValue("external_id"),
+		Status:     uploadProcessing,
+	}
+	upload.IDStr = strconv.FormatInt(upload.ID, 10)
+	if len(data) == 0 {
+		upload.Status = uploadFailed
+		upload.Error = "file is empty"
+	} else {
+		name := r.FormValue("name")
+		if name == "" {
+			name = "Uploaded activity"
+		}
+		s.pending[upload.ID] = strava.Activity{
+			Name:        name,
+			Description: r.FormValue("description"),
+			Trainer:     r.FormValue("trainer") == "1",
+			Commute:     r.FormValue("commute") == "1",
+			Athlete: strava.SummaryAthlete{
+				ID:        s.state.Athlete.ID,
+				FirstName: s.state.Athlete.FirstName,
+				LastName:  s.state.Athlete.LastName,
+			},
+		}
+	}
+	s.state.Uploads[upload.ID] = upload
+	writeJSON(w, http.StatusCreated, upload)
+}
+
+// processUpload turns a pending upload into an activity.
+func (s *Server) processUpload(id int64) {
+	a, ok := s.pending[id]
+	if !ok {
+		return
+	}
+	delete(s.pending, id)
+	a.ID = s.newID()
+	s.state.Activities[a.ID] = a
+	upload := s.state.Uploads[id]
+	upload.Status = uploadReady
+	upload.ActivityID = a.ID
+	s.state.Uploads[id] = upload
+}
+
+func (s *Server) exploreSegments(w http.ResponseWriter, r *http.Request) {
+	var bounds [4]float64
+	parts := strings.Split(r.URL.Query().Get("bounds"), ",")
+	if len(parts) != 4 {
+		badRequest(w, "Segment", "bounds")
+		return
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			badRequest(w, "Segment", "bounds")
+			return
+		}
+		bounds[i] = v
+	}
+	if bounds[0] >= bounds[2] || bounds[1] >= bounds[3] {
+		badRequest(w, "Segment", "bounds")
+		return
+	}
+	activityType := r.URL.Query().Get("activity_type")
+	if activityType == "" {
+		activityType = "riding"
+	}
+	minCat := int(queryInt(r, "min_cat", 0))
+	maxCat := int(queryInt(r, "max_cat", 5))
+	segments := make([]strava.ExplorerSegment, 0, len(s.state.Segments))
+	for _, seg := range s.state.Segments {
+		if len(seg.StartLatLng) == 2 {
+			lat, lng := seg.StartLatLng[0], seg.StartLatLng[1]
+			if lat < bounds[0] || lat > bounds[2] || lng < bounds[1] || lng > bounds[3] {
+				continue
+			}
+		}
+		if seg.ActivityType != "" && explorerActivityType(seg.ActivityType) != activityType {
+			continue
+		}
+		if seg.ClimbCategory < minCat || seg.ClimbCategory > maxCat {
+			continue
+		}
+		segments = append(segments, strava.ExplorerSegment{
+			ID:             seg.ID,
+			Name:           seg.Name,
+			ClimbCategory:  seg.ClimbCategory,
+			AverageGrade:   seg.AverageGrade,
+			StartLatLng:    seg.StartLatLng,
+			EndLatLng:      seg.EndLatLng,
+			ElevDifference: seg.ElevationHigh - seg.ElevationLow,
+			Distance:       seg.Distance,
+			Points:         seg.Map.Polyline,
+			Starred:        s.isStarred(seg.ID),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].ID < segments[j].ID })
+	if len(segments) > 10 {
+		segments = segments[:10]
+	}
+	writeJSON(w, http.StatusOK, strava.ExplorerResponse{Segments: segments})
+}
+
+// explorerActivityType maps a segment's activity type to the explore
+// endpoint's activity_type values.
+func explorerActivityType(activityType string) string {
+	if activityType == "Run" {
+		return "running"
+	}
+	return "riding"
+}
+
+func (s *Server) listStarredSegments(w http.ResponseWriter, r *http.Request) {
+	var segments []strava.DetailedSegment
+	for _, id := range s.state.Starred {
+		if seg, ok := s.state.Segments[id]; ok {
+			seg.Starred = true
+			segments = append(segments, seg)
+		}
+	}
+	writeJSON(w, http.StatusOK, paginate(segments, r))
+}
+
+func (s *Server) starSegment(w http.ResponseWriter, r *http.Request, rawID string) {
+	starred, err := strconv.ParseBool(r.FormValue("starred"))
+	if err != nil {
+		badRequest(w, "Segment", "starred")
+		return
+	}
+	s.getByID(w, rawID, "Segment", func(id int64) (interface{}, bool) {
+		seg, ok := s.state.Segments[id]
+		if !ok {
+			return nil, false
+		}
+		ids := s.state.Starred[:0:0]
+		for _, starredID := range s.state.Starred {
+			if starredID != id {
+				ids = append(ids, starredID)
+			}
+		}
+		if starred {
+			ids = append(ids, id)
+		}
+		s.state.Starred = ids
+		seg.Starred = starred
+		return seg, true
+	})
+}
+
+func (s *Server) isStarred(id int64) bool {
+	for _, starred := range s.state.Starred {
+		if starred == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) listSegmentEfforts(w http.ResponseWriter, r *http.Request) {
+	segmentID := queryInt(r, "segment_id", 0)
+	if _, ok := s.state.Segments[segmentID]; !ok {
+		notFound(w, "Segment")
+		return
+	}
+	start, ok := queryTime(r, "start_date_local")
+	if !ok {
+		badRequest(w, "SegmentEffort", "start_date_local")
+		return
+	}
+	end, ok := queryTime(r, "end_date_local")
+	if !ok {
+		badRequest(w, "SegmentEffort", "end_date_local")
+		return
+	}
+	var efforts []strava.DetailedSegmentEffort
+	for _, e := range s.state.SegmentEfforts {
+		if e.Segment.ID != segmentID {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, e.StartDateLocal); err == nil {
+			if !start.IsZero() && t.Before(start) {
+				continue
+			}
+			if !end.IsZero() && t.After(end) {
+				continue
+			}
+		}
+		efforts = append(efforts, e)
+	}
+	sort.Slice(efforts, func(i, j int) bool {
+		if efforts[i].StartDateLocal != efforts[j].StartDateLocal {
+			return efforts[i].StartDateLocal < efforts[j].StartDateLocal
+		}
+		return efforts[i].ID < efforts[j].ID
+	})
+	writeJSON(w, http.StatusOK, paginate(efforts, r))
+}
+
+// athleteRoutes returns an athlete's routes by ID, leaving out other
+// athletes' private routes.
+func (s *Server) athleteRoutes(athleteID int64) []strava.Route {
+	routes := []strava.Route{}
+	for _, route := range s.state.Routes {
+		if route.Athlete.ID != athleteID || (route.Private && athleteID != s.state.Athlete.ID) {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].ID < routes[j].ID })
+	return routes
+}
+
+// exportRoute writes a route file built from the route's latlng and
+// altitude streams.
+func (s *Server) exportRoute(w http.ResponseWriter, rawID, contentType string, render func(*bytes.Buffer, strava.Route, strava.StreamSet)) {
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		badRequest(w, "Route", "id")
+		return
+	}
+	route, ok := s.state.Routes[id]
+	if !ok {
+		notFound(w, "Route")
+		return
+	}
+	var buf bytes.Buffer
+	render(&buf, route, s.state.RouteStreams[id])
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+func routeGPX(buf *bytes.Buffer, route strava.Route, streams strava.StreamSet) {
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<gpx version="1.1" creator="stravatest" xmlns="http://www.topografix.com/GPX/1/1">` + "\n")
+	buf.WriteString("<trk><name>")
+	xml.EscapeText(buf, []byte(route.Name))
+	buf.WriteString("</name><trkseg>\n")
+	for i, p := range routePoints(streams) {
+		if len(p) != 2 {
+			continue
+		}
+		fmt.Fprintf(buf, `<trkpt lat="%f" lon="%f">`, p[0], p[1])
+		if streams.Altitude != nil && i < len(streams.Altitude.Data) {
+			fmt.Fprintf(buf, "<ele>%.1f</ele>", streams.Altitude.Data[i])
+		}
+		buf.WriteString("</trkpt>\n")
+	}
+	buf.WriteString("</trkseg></trk>\n</gpx>\n")
+}
+
+func routeTCX(buf *bytes.Buffer, route strava.Route, streams strava.StreamSet) {
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">` + "\n")
+	buf.WriteString("<Courses><Course><Name>")
+	xml.EscapeText(buf, []byte(route.Name))
+	buf.WriteString("</Name><Track>\n")
+	for i, p := range routePoints(streams) {
+		if len(p) != 2 {
+			continue
+		}
+		fmt.Fprintf(buf, "<Trackpoint><Position><LatitudeDegrees>%f</LatitudeDegrees><LongitudeDegrees>%f</LongitudeDegrees></Position>", p[0], p[1])
+		if streams.Altitude != nil && i < len(streams.Altitude.Data) {
+			fmt.Fprintf(buf, "<AltitudeMeters>%.1f</AltitudeMeters>", streams.Altitude.Data[i])
+		}
+		buf.WriteString("</Trackpoint>\n")
+	}
+	buf.WriteString("</Track></Course></Courses>\n</TrainingCenterDatabase>\n")
+}
+
+func routePoints(streams strava.StreamSet) []strava.LatLng {
+	if streams.LatLng == nil {
+		return nil
+	}
+	return streams.LatLng.Data
+}
+
+// streamsResponse shapes set the way Strava does: only the requested keys,
+// as an object keyed by type when key_by_type is true and as an array of
+// streams with a "type" field otherwise.
+func streamsResponse(set strava.StreamSet, r *http.Request) interface{} {
+	data, _ := json.Marshal(set)
+	var all map[string]map[string]interface{}
+	json.Unmarshal(data, &all)
+	keys := strings.Split(r.URL.Query().Get("keys"), ",")
+	if r.URL.Query().Get("keys") == "" {
+		keys = keys[:0]
+		for k := range all {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	keyed := make(map[string]map[string]interface{})
+	list := make([]map[string]interface{}, 0, len(keys))
+	for _, k := range keys {
+		stream, ok := all[k]
+		if !ok {
+			continue
+		}
+		keyed[k] = stream
+		withType := map[string]interface{}{"type": k}
+		for field, v := range stream {
+			withType[field] = v
+		}
+		list = append(list, withType)
+	}
+	if keyByType, _ := strconv.ParseBool(r.URL.Query().Get("key_by_type")); keyByType {
+		return keyed
+	}
+	return list
+}
+
+// pageComments applies Strava's cursor pagination. A comment's cursor is
+// its Cursor field, or its ID when that is empty.
+func pageComments(comments []strava.Comment, r *http.Request) []strava.Comment {
+	after := r.URL.Query().Get("after_cursor")
+	start := 0
+	if after != "" {
+		for i, c := range comments {
+			if commentCursor(c) == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	size := int(queryInt(r, "page_size", 30))
+	if size <= 0 {
+		size = 30
+	}
+	end := start + size
+	if end > len(comments) {
+		end = len(comments)
+	}
+	page := make([]strava.Comment, 0, end-start)
+	for _, c := range comments[start:end] {
+		c.Cursor = commentCursor(c)
+		page = append(page, c)
+	}
+	return page
+}
+
+func commentCursor(c strava.Comment) string {
+	if c.Cursor != "" {
+		return c.Cursor
+	}
+	return strconv.FormatInt(c.ID, 10)
+}
+
+// paginate applies the page and per_page query parameters, which default
+// to 1 and 30 as on Strava.
+func paginate[T any](items []T, r *http.Request) []T {
+	page := int(queryInt(r, "page", 1))
+	perPage := int(queryInt(r, "per_page", 30))
+	if page <= 0 {
+		page = 1
+	}
+	if perPage <= 0 {
+		perPage = 30
+	}
+	start := (page - 1) * perPage
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+func queryInt(r *http.Request, key string, def int64) int64 {
+	v, err := strconv.ParseInt(r.URL.Query().Get(key), 10, 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// queryTime parses an RFC 3339 query parameter. A missing parameter yields
+// the zero time.
+func queryTime(r *http.Request, key string) (time.Time, bool) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return time.Time{}, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, err == nil
+}
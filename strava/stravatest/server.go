@@ -0,0 +1,432 @@
+// Package stravatest provides an in-process fake of the Strava API for
+// testing code built on the strava package.
+//
+// A Server holds seedable in-memory State, serves the endpoints covered by
+// strava.Client under /api/v3 and the token refresh and deauthorize
+// endpoints under /oauth, reports rate-limit headers and can be told to
+// fail specific requests:
+//
+//	srv := stravatest.NewServer(&stravatest.State{
+//		Athlete: strava.Athlete{ID: 1, FirstName: "Ada"},
+//	})
+//	defer srv.Close()
+//	client := srv.Client()
+package stravatest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// APIPath is the path prefix the fake API is served under.
+	APIPath = "/api/v3"
+	// OAuthPath is the path prefix of the fake OAuth endpoints.
+	OAuthPath = "/oauth"
+)
+
+// State is the data served by a Server. Maps are keyed by the ID of the
+// resource, or of the parent activity for laps, zones, comments, kudoers
+// and streams.
+type State struct {
+	// Athlete is the authenticated athlete returned by GET /athlete.
+	Athlete strava.Athlete
+	// AthleteZones is returned by GET /athlete/zones.
+	AthleteZones strava.Zones
+
+	Athletes   map[int64]strava.Athlete
+	Stats      map[int64]strava.ActivityStats
+	Activities map[int64]strava.Activity
+	Laps       map[int64][]strava.Lap
+	Zones      map[int64][]strava.ActivityZone
+	Comments   map[int64][]strava.Comment
+	Kudoers    map[int64][]strava.SummaryAthlete
+	Clubs      map[int64]strava.DetailedClub
+	Gear       map[string]strava.DetailedGear
+	Routes     map[int64]strava.Route
+	Uploads    map[int64]strava.Upload
+	Streams    map[int64]strava.StreamSet
+	// SegmentStreams, SegmentEffortStreams and RouteStreams are keyed by
+	// the ID of the segment, segment effort and route respectively.
+	SegmentStreams       map[int64]strava.StreamSet
+	SegmentEffortStreams map[int64]strava.StreamSet
+	RouteStreams         map[int64]strava.StreamSet
+
+	Segments map[int64]strava.DetailedSegment
+	// SegmentEfforts holds the athlete's efforts, matched to segments by
+	// their Segment.ID.
+	SegmentEfforts map[int64]strava.DetailedSegmentEffort
+	// Starred lists the IDs of the athlete's starred segments, in order.
+	Starred []int64
+
+	// ClubMembers and ClubActivities are keyed by club ID. Admins are the
+	// members with Admin set.
+	ClubMembers    map[int64][]strava.ClubAthlete
+	ClubActivities map[int64][]strava.ClubActivity
+	// AthleteClubs lists the IDs of the clubs in Clubs the athlete belongs
+	// to, in order.
+	AthleteClubs []int64
+
+	// ClientID and ClientSecret are the application credentials accepted
+	// by /push_subscriptions and /oauth/token. Empty values accept any
+	// credentials.
+	ClientID     string
+	ClientSecret string
+	// RefreshToken is the refresh token accepted by POST /oauth/token.
+	// Every refresh replaces it, so an old refresh token stops working.
+	// Empty accepts any refresh token.
+	RefreshToken string
+	// Subscription is the application's push subscription, if any.
+	Subscription *strava.Subscription
+	// Deauthorized is set by POST /oauth/deauthorize. From then on every
+	// token is rejected.
+	Deauthorized bool
+}
+
+func (st *State) init() {
+	if st.Athletes == nil {
+		st.Athletes = make(map[int64]strava.Athlete)
+	}
+	if st.Stats == nil {
+		st.Stats = make(map[int64]strava.ActivityStats)
+	}
+	if st.Activities == nil {
+		st.Activities = make(map[int64]strava.Activity)
+	}
+	if st.Laps == nil {
+		st.Laps = make(map[int64][]strava.Lap)
+	}
+	if st.Zones == nil {
+		st.Zones = make(map[int64][]strava.ActivityZone)
+	}
+	if st.Comments == nil {
+		st.Comments = make(map[int64][]strava.Comment)
+	}
+	if st.Kudoers == nil {
+		st.Kudoers = make(map[int64][]strava.SummaryAthlete)
+	}
+	if st.Clubs == nil {
+		st.Clubs = make(map[int64]strava.DetailedClub)
+	}
+	if st.ClubMembers == nil {
+		st.ClubMembers = make(map[int64][]strava.ClubAthlete)
+	}
+	if st.ClubActivities == nil {
+		st.ClubActivities = make(map[int64][]strava.ClubActivity)
+	}
+	if st.Gear == nil {
+		st.Gear = make(map[string]strava.DetailedGear)
+	}
+	if st.Routes == nil {
+		st.Routes = make(map[int64]strava.Route)
+	}
+	if st.Uploads == nil {
+		st.Uploads = make(map[int64]strava.Upload)
+	}
+	if st.Streams == nil {
+		st.Streams = make(map[int64]strava.StreamSet)
+	}
+	if st.SegmentStreams == nil {
+		st.SegmentStreams = make(map[int64]strava.StreamSet)
+	}
+	if st.SegmentEffortStreams == nil {
+		st.SegmentEffortStreams = make(map[int64]strava.StreamSet)
+	}
+	if st.RouteStreams == nil {
+		st.RouteStreams = make(map[int64]strava.StreamSet)
+	}
+	if st.Segments == nil {
+		st.Segments = make(map[int64]strava.DetailedSegment)
+	}
+	if st.SegmentEfforts == nil {
+		st.SegmentEfforts = make(map[int64]strava.DetailedSegmentEffort)
+	}
+}
+
+// RateLimit configures the rate-limit headers. Usage is incremented on
+// every request; once either usage exceeds its limit the server answers
+// 429 Too Many Requests. A zero limit disables that window.
+type RateLimit struct {
+	ShortTermLimit int
+	LongTermLimit  int
+	ShortTermUsage int
+	LongTermUsage  int
+	// The read limits apply to GET and HEAD requests only, on top of the
+	// overall limits, and are reported as X-ReadRateLimit-*.
+	ReadShortTermLimit int
+	ReadLongTermLimit  int
+	ReadShortTermUsage int
+	ReadLongTermUsage  int
+}
+
+// Fault makes the server fail matching requests instead of serving them.
+type Fault struct {
+	// Method and Path select the request, with Path relative to APIPath
+	// (e.g. "/athlete"). An empty Method matches any method.
+	Method string
+	Path   string
+	// Status is the HTTP status to answer with.
+	Status int
+	// Message and Errors fill in the Strava fault body.
+	Message string
+	Errors  []strava.Error
+	// Header is added to the response, e.g. a Retry-After.
+	Header http.Header
+	// Times is how many requests the fault applies to. Zero means every
+	// matching request.
+	Times int
+}
+
+// Server is a fake Strava API backed by an httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	state     *State
+	rateLimit RateLimit
+	faults    []*Fault
+	nextID    int64
+	requests  []*http.Request
+	// pending holds the activities of uploads that have not been
+	// processed yet, keyed by upload ID.
+	pending map[int64]strava.Activity
+}
+
+// NewServer starts a Server serving state. A nil state starts empty.
+func NewServer(state *State) *Server {
+	if state == nil {
+		state = &State{}
+	}
+	state.init()
+	s := &Server{state: state, nextID: 1000, pending: make(map[int64]strava.Activity)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseURL returns the API root to pass to strava.WithBaseURL.
+func (s *Server) BaseURL() string {
+	return s.URL + APIPath
+}
+
+// OAuthBaseURL returns the OAuth root to pass to strava.WithOAuthBaseURL.
+func (s *Server) OAuthBaseURL() string {
+	return s.URL + OAuthPath
+}
+
+// Client returns a strava.Client talking to s with a dummy access token.
+func (s *Server) Client(opts ...strava.Option) *strava.Client {
+	opts = append([]strava.Option{strava.WithBaseURL(s.BaseURL()), strava.WithOAuthBaseURL(s.OAuthBaseURL())}, opts...)
+	return strava.NewClient(&oauth2.Token{AccessToken: "stravatest"}, opts...)
+}
+
+// Update runs fn with exclusive access to the server's state.
+func (s *Server) Update(fn func(st *State)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.state)
+	s.state.init()
+}
+
+// SetRateLimit replaces the rate-limit configuration.
+func (s *Server) SetRateLimit(rl RateLimit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = rl
+}
+
+// InjectFault registers f. Faults are checked in the order they were added.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests received so far, oldest first.
+func (s *Server) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Clone(r.Context()))
+
+	if r.URL.Path == OAuthPath+"/token" && r.Method == "POST" {
+		s.refreshToken(w, r)
+		return
+	}
+	if r.URL.Path == OAuthPath+"/deauthorize" && r.Method == "POST" {
+		s.deauthorize(w, r)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, APIPath+"/") {
+		writeFault(w, http.StatusNotFound, "Record Not Found", strava.Error{Resource: "resource", Code: "not found"})
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, APIPath)
+
+	limited := s.countRequest(w.Header(), r.Method == "GET" || r.Method == "HEAD")
+	if f := s.matchFault(r.Method, path); f != nil {
+		for k, v := range f.Header {
+			w.Header()[k] = v
+		}
+		writeFault(w, f.Status, f.Message, f.Errors...)
+		return
+	}
+	if limited {
+		writeFault(w, http.StatusTooManyRequests, "Rate Limit Exceeded", strava.Error{Resource: "Application", Field: "rate limit", Code: "exceeded"})
+		return
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if parts[0] == "push_subscriptions" {
+		// Authenticated with application credentials, not a token.
+		s.routeSubscriptions(w, r, parts)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeFault(w, http.StatusUnauthorized, "Authorization Error", strava.Error{Resource: "Athlete", Field: "access_token", Code: "missing"})
+		return
+	}
+	if s.state.Deauthorized {
+		writeFault(w, http.StatusUnauthorized, "Authorization Error", strava.Error{Resource: "Athlete", Field: "access_token", Code: "invalid"})
+		return
+	}
+	s.route(w, r, parts)
+}
+
+// countRequest bumps usage, writes the rate-limit headers and reports
+// whether the request is over any limit. Read requests also count against
+// the read limits.
+func (s *Server) countRequest(h http.Header, read bool) bool {
+	rl := &s.rateLimit
+	limited := false
+	if rl.ShortTermLimit != 0 || rl.LongTermLimit != 0 {
+		rl.ShortTermUsage++
+		rl.LongTermUsage++
+		h.Set("X-RateLimit-Limit", strconv.Itoa(rl.ShortTermLimit)+","+strconv.Itoa(rl.LongTermLimit))
+		h.Set("X-RateLimit-Usage", strconv.Itoa(rl.ShortTermUsage)+","+strconv.Itoa(rl.LongTermUsage))
+		limited = (rl.ShortTermLimit > 0 && rl.ShortTermUsage > rl.ShortTermLimit) ||
+			(rl.LongTermLimit > 0 && rl.LongTermUsage > rl.LongTermLimit)
+	}
+	if rl.ReadShortTermLimit != 0 || rl.ReadLongTermLimit != 0 {
+		if read {
+			rl.ReadShortTermUsage++
+			rl.ReadLongTermUsage++
+			limited = limited ||
+				(rl.ReadShortTermLimit > 0 && rl.ReadShortTermUsage > rl.ReadShortTermLimit) ||
+				(rl.ReadLongTermLimit > 0 && rl.ReadLongTermUsage > rl.ReadLongTermLimit)
+		}
+		h.Set("X-ReadRateLimit-Limit", strconv.Itoa(rl.ReadShortTermLimit)+","+strconv.Itoa(rl.ReadLongTermLimit))
+		h.Set("X-ReadRateLimit-Usage", strconv.Itoa(rl.ReadShortTermUsage)+","+strconv.Itoa(rl.ReadLongTermUsage))
+	}
+	return limited
+}
+
+func (s *Server) matchFault(method, path string) *Fault {
+	for i, f := range s.faults {
+		if (f.Method != "" && f.Method != method) || f.Path != path {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// refreshToken serves the refresh_token grant of Strava's OAuth token
+// endpoint, issuing a new access token and a rotated refresh token.
+func (s *Server) refreshToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		badRequest(w, "RefreshToken", "body")
+		return
+	}
+	if !s.validClient(r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")) {
+		badRequest(w, "Application", "client_id")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "refresh_token" {
+		badRequest(w, "RefreshToken", "grant_type")
+		return
+	}
+	refresh := r.PostForm.Get("refresh_token")
+	if refresh == "" || s.state.Deauthorized || (s.state.RefreshToken != "" && refresh != s.state.RefreshToken) {
+		badRequest(w, "RefreshToken", "refresh_token")
+		return
+	}
+	id := strconv.FormatInt(s.newID(), 10)
+	s.state.RefreshToken = "refresh-" + id
+	const lifetime = 6 * time.Hour
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token_type":    "Bearer",
+		"access_token":  "access-" + id,
+		"refresh_token": s.state.RefreshToken,
+		"expires_at":    time.Now().Add(lifetime).Unix(),
+		"expires_in":    int(lifetime / time.Second),
+	})
+}
+
+// deauthorize serves Strava's OAuth deauthorize endpoint, which lives
+// outside APIPath and accepts the token as a header or parameter.
+func (s *Server) deauthorize(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == r.Header.Get("Authorization") {
+		token = r.FormValue("access_token")
+	}
+	if token == "" || s.state.Deauthorized {
+		writeFault(w, http.StatusUnauthorized, "Authorization Error", strava.Error{Resource: "Athlete", Field: "access_token", Code: "invalid"})
+		return
+	}
+	s.state.Deauthorized = true
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": token})
+}
+
+func (s *Server) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeFault(w http.ResponseWriter, status int, message string, errs ...strava.Error) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	if errs == nil {
+		errs = []strava.Error{}
+	}
+	writeJSON(w, status, strava.Fault{Message: message, Errors: errs})
+}
+
+func notFound(w http.ResponseWriter, resource string) {
+	writeFault(w, http.StatusNotFound, "Record Not Found", strava.Error{Resource: resource, Field: "id", Code: "invalid"})
+}
+
+func badRequest(w http.ResponseWriter, resource, field string) {
+	writeFault(w, http.StatusBadRequest, "Bad Request", strava.Error{Resource: resource, Field: field, Code: "invalid"})
+}
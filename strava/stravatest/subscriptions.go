@@ -0,0 +1,160 @@
+package stravatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+)
+
+// callbackTimeout is how long Strava waits for a callback to answer the
+// subscription challenge.
+const callbackTimeout = 2 * time.Second
+
+// routeSubscriptions serves /push_subscriptions. The caller holds s.mu.
+func (s *Server) routeSubscriptions(w http.ResponseWriter, r *http.Request, parts []string) {
+	if err := r.ParseForm(); err != nil {
+		badRequest(w, "PushSubscription", "body")
+		return
+	}
+	if !s.validClient(r.Form.Get("client_id"), r.Form.Get("client_secret")) {
+		writeFault(w, http.StatusUnauthorized, "Authorization Error", strava.Error{Resource: "Application", Field: "client_secret", Code: "invalid"})
+		return
+	}
+	switch {
+	case match(r, "POST", parts, "push_subscriptions"):
+		s.createSubscription(w, r)
+	case match(r, "GET", parts, "push_subscriptions"):
+		subs := []strava.Subscription{}
+		if s.state.Subscription != nil {
+			subs = append(subs, *s.state.Subscription)
+		}
+		writeJSON(w, http.StatusOK, subs)
+	case match(r, "DELETE", parts, "push_subscriptions", "*"):
+		id, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			badRequest(w, "PushSubscription", "id")
+			return
+		}
+		if s.state.Subscription == nil || s.state.Subscription.ID != id {
+			notFound(w, "PushSubscription")
+			return
+		}
+		s.state.Subscription = nil
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeFault(w, http.StatusNotFound, "Record Not Found", strava.Error{Resource: "resource", Code: "not found"})
+	}
+}
+
+func (s *Server) validClient(id, secret string) bool {
+	if id == "" || secret == "" {
+		return false
+	}
+	return (s.state.ClientID == "" || id == s.state.ClientID) &&
+		(s.state.ClientSecret == "" || secret == s.state.ClientSecret)
+}
+
+// createSubscription verifies the callback the way Strava does, by sending
+// it a hub.challenge and expecting it echoed back, before subscribing. The
+// caller holds s.mu, which is released during the verification request so
+// the callback can call back into the server.
+func (s *Server) createSubscription(w http.ResponseWriter, r *http.Request) {
+	if s.state.Subscription != nil {
+		writeFault(w, http.StatusBadRequest, "Bad Request", strava.Error{Resource: "PushSubscription", Code: "already exists"})
+		return
+	}
+	callbackURL := r.PostForm.Get("callback_url")
+	if _, err := url.ParseRequestURI(callbackURL); err != nil {
+		badRequest(w, "PushSubscription", "callback url")
+		return
+	}
+	s.mu.Unlock()
+	err := verifyCallback(callbackURL, r.PostForm.Get("verify_token"))
+	s.mu.Lock()
+	if err != nil {
+		writeFault(w, http.StatusBadRequest, "Bad Request", strava.Error{Resource: "PushSubscription", Field: "callback url", Code: err.Error()})
+		return
+	}
+	// Another subscription may have been created while unlocked.
+	if s.state.Subscription != nil {
+		writeFault(w, http.StatusBadRequest, "Bad Request", strava.Error{Resource: "PushSubscription", Code: "already exists"})
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	sub := &strava.Subscription{
+		ID:            s.newID(),
+		ResourceState: 2,
+		CallbackURL:   callbackURL,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if id, err := strconv.ParseInt(r.PostForm.Get("client_id"), 10, 64); err == nil {
+		sub.ApplicationID = id
+	}
+	s.state.Subscription = sub
+	writeJSON(w, http.StatusCreated, struct {
+		ID int64 `json:"id"`
+	}{sub.ID})
+}
+
+func verifyCallback(callbackURL, verifyToken string) error {
+	challenge := strconv.FormatInt(time.Now().UnixNano(), 36)
+	u, _ := url.Parse(callbackURL)
+	q := u.Query()
+	q.Set("hub.mode", "subscribe")
+	q.Set("hub.challenge", challenge)
+	q.Set("hub.verify_token", verifyToken)
+	u.RawQuery = q.Encode()
+	client := &http.Client{Timeout: callbackTimeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return errors.New("GET to callback URL failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("GET to callback URL does not return 200")
+	}
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body["hub.challenge"] != challenge {
+		return errors.New("callback URL did not echo hub.challenge")
+	}
+	return nil
+}
+
+// PushEvent delivers ev to the subscription's callback URL the way Strava
+// does. It sets SubscriptionID, and sets EventTime to the current time if
+// it is zero. It fails if there is no subscription or the callback does not
+// answer 200 within two seconds.
+func (s *Server) PushEvent(ev strava.WebhookEvent) error {
+	s.mu.Lock()
+	sub := s.state.Subscription
+	s.mu.Unlock()
+	if sub == nil {
+		return errors.New("stravatest: no push subscription")
+	}
+	ev.SubscriptionID = sub.ID
+	if ev.EventTime == 0 {
+		ev.EventTime = time.Now().Unix()
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: callbackTimeout}
+	resp, err := client.Post(sub.CallbackURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stravatest: callback answered %s", resp.Status)
+	}
+	return nil
+}
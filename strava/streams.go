@@ -0,0 +1,147 @@
+package strava
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// Stream types accepted in the keys parameter of the streams endpoints.
+const (
+	StreamTime           = "time"
+	StreamDistance       = "distance"
+	StreamLatLng         = "latlng"
+	StreamAltitude       = "altitude"
+	StreamVelocitySmooth = "velocity_smooth"
+	StreamHeartrate      = "heartrate"
+	StreamCadence        = "cadence"
+	StreamWatts          = "watts"
+	StreamTemp           = "temp"
+	StreamMoving         = "moving"
+	StreamGradeSmooth    = "grade_smooth"
+)
+
+// BaseStream holds the metadata shared by every stream.
+type BaseStream struct {
+	// OriginalSize is the number of points before any downsampling.
+	OriginalSize int `json:"original_size"`
+	// Resolution is "low", "medium" or "high".
+	Resolution string `json:"resolution"`
+	// SeriesType is the base series used when downsampling, "distance" or
+	// "time".
+	SeriesType string `json:"series_type"`
+}
+
+// TimeStream is seconds since the start of the activity.
+type TimeStream struct {
+	BaseStream
+	Data []int `json:"data"`
+}
+
+// DistanceStream is meters since the start of the activity.
+type DistanceStream struct {
+	BaseStream
+	Data []float64 `json:"data"`
+}
+
+type LatLngStream struct {
+	BaseStream
+	Data []LatLng `json:"data"`
+}
+
+// AltitudeStream is meters above sea level.
+type AltitudeStream struct {
+	BaseStream
+	Data []float64 `json:"data"`
+}
+
+// SmoothVelocityStream is meters per second.
+type SmoothVelocityStream struct {
+	BaseStream
+	Data []float64 `json:"data"`
+}
+
+// HeartrateStream is beats per minute.
+type HeartrateStream struct {
+	BaseStream
+	Data []int `json:"data"`
+}
+
+// CadenceStream is rotations per minute.
+type CadenceStream struct {
+	BaseStream
+	Data []int `json:"data"`
+}
+
+// PowerStream is watts.
+type PowerStream struct {
+	BaseStream
+	Data []int `json:"data"`
+}
+
+// TemperatureStream is degrees Celsius.
+type TemperatureStream struct {
+	BaseStream
+	Data []int `json:"data"`
+}
+
+type MovingStream struct {
+	BaseStream
+	Data []bool `json:"data"`
+}
+
+// SmoothGradeStream is percent grade.
+type SmoothGradeStream struct {
+	BaseStream
+	Data []float64 `json:"data"`
+}
+
+// StreamSet holds the streams returned for an activity, segment, segment
+// effort or route. Streams that were not requested or are not available
+// are nil.
+type StreamSet struct {
+	Time           *TimeStream           `json:"time,omitempty"`
+	Distance       *DistanceStream       `json:"distance,omitempty"`
+	LatLng         *LatLngStream         `json:"latlng,omitempty"`
+	Altitude       *AltitudeStream       `json:"altitude,omitempty"`
+	VelocitySmooth *SmoothVelocityStream `json:"velocity_smooth,omitempty"`
+	Heartrate      *HeartrateStream      `json:"heartrate,omitempty"`
+	Cadence        *CadenceStream        `json:"cadence,omitempty"`
+	Watts          *PowerStream          `json:"watts,omitempty"`
+	Temp           *TemperatureStream    `json:"temp,omitempty"`
+	Moving         *MovingStream         `json:"moving,omitempty"`
+	GradeSmooth    *SmoothGradeStream    `json:"grade_smooth,omitempty"`
+}
+
+// UnmarshalJSON accepts both response shapes: an object keyed by stream
+// type (key_by_type=true) and an array of streams each carrying a "type"
+// field (key_by_type=false).
+func (s *StreamSet) UnmarshalJSON(data []byte) error {
+	type keyed StreamSet
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '[' {
+		return json.Unmarshal(data, (*keyed)(s))
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	byType := make(map[string]json.RawMessage, len(list))
+	for _, raw := range list {
+		var head struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(raw, &head); err != nil {
+			return err
+		}
+		if head.Type == "" {
+			return errors.New("strava: stream without a type")
+		}
+		byType[head.Type] = raw
+	}
+	keyedData, err := json.Marshal(byType)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(keyedData, (*keyed)(s))
+}
@@ -0,0 +1,83 @@
+package strava_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+	"github.com/yrludev/strava-golang-api-wrapper/strava/stravatest"
+)
+
+func TestStreamSetUnmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "key_by_type=true",
+			body: `{
+				"time": {"data": [0, 1, 2], "series_type": "distance", "original_size": 3, "resolution": "high"},
+				"latlng": {"data": [[37.8, -122.4], [37.81, -122.41], [37.82, -122.42]], "series_type": "distance", "original_size": 3, "resolution": "high"},
+				"moving": {"data": [false, true, true], "series_type": "distance", "original_size": 3, "resolution": "high"}
+			}`,
+		},
+		{
+			name: "key_by_type=false",
+			body: `[
+				{"type": "time", "data": [0, 1, 2], "series_type": "distance", "original_size": 3, "resolution": "high"},
+				{"type": "latlng", "data": [[37.8, -122.4], [37.81, -122.41], [37.82, -122.42]], "series_type": "distance", "original_size": 3, "resolution": "high"},
+				{"type": "moving", "data": [false, true, true], "series_type": "distance", "original_size": 3, "resolution": "high"}
+			]`,
+		},
+	}
+	base := strava.BaseStream{OriginalSize: 3, Resolution: "high", SeriesType: "distance"}
+	want := strava.StreamSet{
+		Time:   &strava.TimeStream{BaseStream: base, Data: []int{0, 1, 2}},
+		LatLng: &strava.LatLngStream{BaseStream: base, Data: []strava.LatLng{{37.8, -122.4}, {37.81, -122.41}, {37.82, -122.42}}},
+		Moving: &strava.MovingStream{BaseStream: base, Data: []bool{false, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strava.StreamSet
+			if err := json.Unmarshal([]byte(tt.body), &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestStreamSetUnmarshalMissingType(t *testing.T) {
+	var got strava.StreamSet
+	if err := json.Unmarshal([]byte(`[{"data": [0, 1, 2]}]`), &got); err == nil {
+		t.Error("Unmarshal succeeded, want an error for a stream without a type")
+	}
+}
+
+func TestGetActivityStreams(t *testing.T) {
+	base := strava.BaseStream{OriginalSize: 3, Resolution: "high", SeriesType: "time"}
+	streams := strava.StreamSet{
+		Time:      &strava.TimeStream{BaseStream: base, Data: []int{0, 1, 2}},
+		Distance:  &strava.DistanceStream{BaseStream: base, Data: []float64{0, 4.5, 9.25}},
+		Heartrate: &strava.HeartrateStream{BaseStream: base, Data: []int{120, 125, 131}},
+		Watts:     &strava.PowerStream{BaseStream: base, Data: []int{180, 210, 205}},
+	}
+	srv := stravatest.NewServer(&stravatest.State{Streams: map[int64]strava.StreamSet{7: streams}})
+	defer srv.Close()
+	client := srv.Client()
+
+	keys := []string{strava.StreamTime, strava.StreamDistance, strava.StreamHeartrate}
+	want := strava.StreamSet{Time: streams.Time, Distance: streams.Distance, Heartrate: streams.Heartrate}
+	for _, keyByType := range []bool{true, false} {
+		got, err := client.GetActivityStreams(7, keys, keyByType)
+		if err != nil {
+			t.Fatalf("GetActivityStreams(keyByType=%v): %v", keyByType, err)
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("GetActivityStreams(keyByType=%v) = %+v, want %+v", keyByType, *got, want)
+		}
+	}
+}
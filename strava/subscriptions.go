@@ -0,0 +1,130 @@
+package strava
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Subscription is an application's webhook push subscription. Strava
+// allows one per application.
+type Subscription struct {
+	ID            int64  `json:"id"`
+	ResourceState int    `json:"resource_state"`
+	ApplicationID int64  `json:"application_id"`
+	CallbackURL   string `json:"callback_url"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// SubscriptionClient manages push subscriptions. These endpoints are
+// authenticated with the application's client ID and secret instead of an
+// athlete token, so it is separate from Client.
+type SubscriptionClient struct {
+	ClientID     string
+	ClientSecret string
+
+	api *Client
+}
+
+// NewSubscriptionClient returns a SubscriptionClient for the application.
+// It accepts the same options as NewClient and shares its retry and
+// rate-limit handling.
+func NewSubscriptionClient(clientID, clientSecret string, opts ...Option) *SubscriptionClient {
+	o := newOptions(opts)
+	return &SubscriptionClient{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		api: &Client{
+			HTTPClient: &http.Client{Transport: o.transport, Timeout: o.timeout},
+			Retry:      DefaultRetryPolicy(),
+			apiBase:    o.baseURL,
+			userAgent:  o.userAgent,
+		},
+	}
+}
+
+func (s *SubscriptionClient) credentials() url.Values {
+	return url.Values{"client_id": {s.ClientID}, "client_secret": {s.ClientSecret}}
+}
+
+// CreateSubscription subscribes the application to push events. Before
+// answering, Strava sends a GET with hub.challenge and verifyToken to
+// callbackURL, which must echo the challenge within two seconds (see
+// WebhookHandler). A second subscription fails with ErrSubscriptionExists.
+func (s *SubscriptionClient) CreateSubscription(callbackURL, verifyToken string) (*Subscription, error) {
+	return s.CreateSubscriptionCtx(context.Background(), callbackURL, verifyToken)
+}
+
+func (s *SubscriptionClient) CreateSubscriptionCtx(ctx context.Context, callbackURL, verifyToken string) (*Subscription, error) {
+	form := s.credentials()
+	form.Set("callback_url", callbackURL)
+	form.Set("verify_token", verifyToken)
+	req, err := http.NewRequestWithContext(ctx, "POST", s.api.baseURL()+"/push_subscriptions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.api.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var sub Subscription
+	if err := json.NewDecoder(resp.Body).Decode(&sub); err != nil {
+		return nil, err
+	}
+	if sub.CallbackURL == "" {
+		sub.CallbackURL = callbackURL
+	}
+	return &sub, nil
+}
+
+// ListSubscriptions returns the application's subscriptions: none or one.
+func (s *SubscriptionClient) ListSubscriptions() ([]Subscription, error) {
+	return s.ListSubscriptionsCtx(context.Background())
+}
+
+func (s *SubscriptionClient) ListSubscriptionsCtx(ctx context.Context) ([]Subscription, error) {
+	url := s.api.baseURL() + "/push_subscriptions?" + s.credentials().Encode()
+	resp, err := s.api.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	var subs []Subscription
+	if err := json.NewDecoder(resp.Body).Decode(&subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (s *SubscriptionClient) DeleteSubscription(id int64) error {
+	return s.DeleteSubscriptionCtx(context.Background(), id)
+}
+
+func (s *SubscriptionClient) DeleteSubscriptionCtx(ctx context.Context, id int64) error {
+	url := fmt.Sprintf("%s/push_subscriptions/%d?%s", s.api.baseURL(), id, s.credentials().Encode())
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.api.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+	return nil
+}
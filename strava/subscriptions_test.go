@@ -0,0 +1,76 @@
+package strava_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+	"github.com/yrludev/strava-golang-api-wrapper/strava/stravatest"
+)
+
+func TestCreateSubscriptionCallbackCallsServer(t *testing.T) {
+	srv := stravatest.NewServer(nil)
+	defer srv.Close()
+	subs := strava.NewSubscriptionClient("1", "secret", strava.WithBaseURL(srv.BaseURL()))
+
+	hook := strava.NewWebhookHandler(testVerifyToken)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Call back into the server while it waits for the challenge echo.
+		if _, err := subs.ListSubscriptions(); err != nil {
+			t.Errorf("ListSubscriptions during verification: %v", err)
+		}
+		hook.ServeHTTP(w, r)
+	}))
+	defer callback.Close()
+
+	start := time.Now()
+	if _, err := subs.CreateSubscription(callback.URL, testVerifyToken); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CreateSubscription took %v", elapsed)
+	}
+}
+
+func TestPushEventEventTime(t *testing.T) {
+	events := make(chan strava.WebhookEvent, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			strava.NewWebhookHandler(testVerifyToken).ServeHTTP(w, r)
+			return
+		}
+		var ev strava.WebhookEvent
+		json.NewDecoder(r.Body).Decode(&ev)
+		events <- ev
+	}))
+	defer callback.Close()
+	srv := stravatest.NewServer(nil)
+	defer srv.Close()
+	subs := strava.NewSubscriptionClient("1", "secret", strava.WithBaseURL(srv.BaseURL()))
+	sub, err := subs.CreateSubscription(callback.URL, testVerifyToken)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	before := time.Now().Unix()
+	for _, eventTime := range []int64{0, 1700000000} {
+		ev := activityCreated
+		ev.EventTime = eventTime
+		if err := srv.PushEvent(ev); err != nil {
+			t.Fatalf("PushEvent: %v", err)
+		}
+		got := <-events
+		if got.SubscriptionID != sub.ID {
+			t.Errorf("SubscriptionID = %d, want %d", got.SubscriptionID, sub.ID)
+		}
+		if eventTime == 0 && got.EventTime < before {
+			t.Errorf("zero EventTime became %d, want the current time", got.EventTime)
+		}
+		if eventTime != 0 && got.EventTime != eventTime {
+			t.Errorf("EventTime = %d, want it kept at %d", got.EventTime, eventTime)
+		}
+	}
+}
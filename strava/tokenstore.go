@@ -0,0 +1,298 @@
+package strava
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrTokenNotFound is returned by a TokenStore that has no token for the
+// requested athlete.
+var ErrTokenNotFound = errors.New("strava: token not found")
+
+// TokenStore persists OAuth tokens per athlete. Strava rotates refresh
+// tokens, so the latest one must be saved every time it changes. Delete
+// is called once an athlete deauthorizes; deleting a missing token is not
+// an error.
+type TokenStore interface {
+	Load(athleteID int64) (*oauth2.Token, error)
+	Save(athleteID int64, tok *oauth2.Token) error
+	Delete(athleteID int64) error
+}
+
+// NewClientWithStore returns a refreshing Client for athleteID using the
+// token held in store. Every token minted afterwards is saved back.
+func NewClientWithStore(clientID, clientSecret string, store TokenStore, athleteID int64, opts ...Option) (*Client, error) {
+	tok, err := store.Load(athleteID)
+	if err != nil {
+		return nil, err
+	}
+	c := NewClientFromToken(clientID, clientSecret, tok, func(tok *oauth2.Token) error {
+		return store.Save(athleteID, tok)
+	}, opts...)
+	c.store = store
+	c.athleteID = athleteID
+	return c, nil
+}
+
+// MemoryTokenStore is an in-memory TokenStore, mainly useful in tests.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[int64]oauth2.Token
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[int64]oauth2.Token)}
+}
+
+func (s *MemoryTokenStore) Load(athleteID int64) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tok, ok := s.tokens[athleteID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &tok, nil
+}
+
+func (s *MemoryTokenStore) Save(athleteID int64, tok *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens == nil {
+		s.tokens = make(map[int64]oauth2.Token)
+	}
+	s.tokens[athleteID] = *tok
+	return nil
+}
+
+func (s *MemoryTokenStore) Delete(athleteID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, athleteID)
+	return nil
+}
+
+// Lock file tuning for FileTokenStore. A lock older than staleLockAge is
+// assumed to belong to a crashed process and is broken. Locks are held for
+// milliseconds, and staleLockAge is kept well under lockTimeout so that
+// waiters outlast a crashed holder's lock instead of failing.
+const (
+	lockPollInterval = 10 * time.Millisecond
+	lockTimeout      = 10 * time.Second
+	staleLockAge     = 5 * time.Second
+)
+
+// FileTokenStore keeps tokens for all athletes in a single JSON file.
+// Writes go to a temporary file that is renamed into place, and a sibling
+// ".lock" file serializes access between processes.
+type FileTokenStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{Path: path}
+}
+
+func (s *FileTokenStore) Load(athleteID int64) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := tokens[strconv.FormatInt(athleteID, 10)]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return tok, nil
+}
+
+func (s *FileTokenStore) Save(athleteID int64, tok *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	tokens[strconv.FormatInt(athleteID, 10)] = tok
+	return s.write(tokens)
+}
+
+func (s *FileTokenStore) Delete(athleteID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	key := strconv.FormatInt(athleteID, 10)
+	if _, ok := tokens[key]; !ok {
+		return nil
+	}
+	delete(tokens, key)
+	return s.write(tokens)
+}
+
+func (s *FileTokenStore) read() (map[string]*oauth2.Token, error) {
+	tokens := make(map[string]*oauth2.Token)
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("strava: decoding token store %s: %w", s.Path, err)
+	}
+	return tokens, nil
+}
+
+func (s *FileTokenStore) write(tokens map[string]*oauth2.Token) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.Path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// lock takes the cross-process lock file, waiting up to lockTimeout. The
+// file holds a random nonce so that only its owner removes it.
+func (s *FileTokenStore) lock() (func(), error) {
+	path := s.Path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	nonce, err := lockNonce()
+	if err != nil {
+		return nil, err
+	}
+	contents := fmt.Sprintf("%d %s\n", os.Getpid(), nonce)
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err := createLockFile(path, contents)
+		if err == nil {
+			return func() { releaseLock(path, contents) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if breakStaleLock(path) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("strava: timed out waiting for lock %s", path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// createLockFile creates path holding contents. It fails with an error
+// wrapping os.ErrExist if path already exists.
+func createLockFile(path, contents string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(contents)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// releaseLock removes the lock file at path if it still holds contents.
+// It can only hold something else if the lock outlived staleLockAge and
+// was broken, in which case the file belongs to the new owner.
+func releaseLock(path, contents string) {
+	if data, err := os.ReadFile(path); err == nil && string(data) == contents {
+		os.Remove(path)
+	}
+}
+
+// breakStaleLock removes the lock file at path if it is older than
+// staleLockAge and reports whether it did. Waiters that find the same
+// stale lock take turns through a ".break" file and check again before
+// removing it, so none of them removes the fresh lock another has just
+// taken in its place.
+func breakStaleLock(path string) bool {
+	if !isStale(path) {
+		return false
+	}
+	breakPath := path + ".break"
+	if err := createLockFile(breakPath, ""); err != nil {
+		// Another waiter is breaking the lock. It holds the break file
+		// only for an instant, so an old one was left by a crash.
+		if isStale(breakPath) {
+			os.Remove(breakPath)
+		}
+		return false
+	}
+	defer os.Remove(breakPath)
+	return isStale(path) && os.Remove(path) == nil
+}
+
+func isStale(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > staleLockAge
+}
+
+func lockNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,152 @@
+package strava
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// onlyFiles fails t unless dir holds exactly the named files.
+func onlyFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if fmt.Sprint(got) != fmt.Sprint(names) {
+		t.Errorf("%s holds %v, want %v", dir, got, names)
+	}
+}
+
+func TestFileTokenStoreConcurrentSave(t *testing.T) {
+	for _, staleLock := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stale lock %v", staleLock), func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "tokens.json")
+			if staleLock {
+				// Every store finds the same stale lock and races to break it.
+				if err := os.WriteFile(path+".lock", []byte("99999 crashed\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				old := time.Now().Add(-2 * staleLockAge)
+				if err := os.Chtimes(path+".lock", old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			const n = 20
+			var wg sync.WaitGroup
+			errs := make(chan error, n)
+			for i := 1; i <= n; i++ {
+				wg.Add(1)
+				go func(id int64) {
+					defer wg.Done()
+					errs <- NewFileTokenStore(path).Save(id, &oauth2.Token{AccessToken: fmt.Sprintf("access-%d", id)})
+				}(int64(i))
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+			}
+
+			store := NewFileTokenStore(path)
+			for i := int64(1); i <= n; i++ {
+				tok, err := store.Load(i)
+				if err != nil {
+					t.Fatalf("Load(%d): %v", i, err)
+				}
+				if want := fmt.Sprintf("access-%d", i); tok.AccessToken != want {
+					t.Errorf("Load(%d).AccessToken = %q, want %q", i, tok.AccessToken, want)
+				}
+			}
+			onlyFiles(t, dir, "tokens.json")
+		})
+	}
+}
+
+func TestFileTokenStoreBreaksStaleLock(t *testing.T) {
+	tests := []struct {
+		name  string
+		stale []string
+	}{
+		{"lock", []string{".lock"}},
+		{"lock and break file", []string{".lock", ".lock.break"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "tokens.json")
+			old := time.Now().Add(-2 * staleLockAge)
+			for _, suffix := range tt.stale {
+				if err := os.WriteFile(path+suffix, []byte("99999 crashed\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(path+suffix, old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			start := time.Now()
+			if err := NewFileTokenStore(path).Save(1, &oauth2.Token{AccessToken: "access"}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Save took %v to break a stale lock", elapsed)
+			}
+			onlyFiles(t, dir, "tokens.json")
+		})
+	}
+}
+
+func TestFileTokenStoreKeepsFreshLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := os.WriteFile(path+".lock", []byte("99999 alive\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if breakStaleLock(path + ".lock") {
+		t.Error("breakStaleLock broke a fresh lock")
+	}
+	if _, err := os.Stat(path + ".lock"); err != nil {
+		t.Errorf("fresh lock is gone: %v", err)
+	}
+}
+
+func TestReleaseLockKeepsOtherOwner(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tokens.json")
+	store := NewFileTokenStore(path)
+
+	unlock, err := store.lock()
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	// Stand in for another process that broke our lock and took its own.
+	if err := os.WriteFile(path+".lock", []byte("99999 other\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	data, err := os.ReadFile(path + ".lock")
+	if err != nil || string(data) != "99999 other\n" {
+		t.Fatalf("other owner's lock = %q, %v; want it kept", data, err)
+	}
+
+	os.Remove(path + ".lock")
+	unlock, err = store.lock()
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	unlock()
+	onlyFiles(t, dir)
+}
@@ -0,0 +1,137 @@
+package strava
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// ErrUploadFailed is wrapped by WaitForUpload when Strava rejects an
+// uploaded file.
+var ErrUploadFailed = errors.New("strava: upload failed")
+
+// Upload polling bounds for WaitForUpload. Strava asks clients not to
+// poll more than about once a second.
+const (
+	uploadPollMin = time.Second
+	uploadPollMax = 10 * time.Second
+)
+
+// UploadParams describes a file passed to CreateUpload.
+type UploadParams struct {
+	// DataType is one of "fit", "fit.gz", "tcx", "tcx.gz", "gpx" or
+	// "gpx.gz".
+	DataType    string
+	Name        string
+	Description string
+	// ExternalID is an identifier of your choosing, echoed back on the
+	// Upload.
+	ExternalID string
+	Trainer    bool
+	Commute    bool
+}
+
+// CreateUpload uploads an activity file. The file is streamed to Strava
+// rather than buffered, so the request is never retried. The returned
+// Upload is usually still processing; see WaitForUpload.
+func (c *Client) CreateUpload(file io.Reader, params UploadParams) (*Upload, error) {
+	return c.CreateUploadCtx(context.Background(), file, params)
+}
+
+func (c *Client) CreateUploadCtx(ctx context.Context, file io.Reader, params UploadParams) (*Upload, error) {
+	if params.DataType == "" {
+		return nil, errors.New("strava: upload data type is required")
+	}
+	url := c.baseURL() + "/uploads"
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeUploadForm(mw, file, params))
+	}()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := c.do(req)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, newAPIError(resp)
+	}
+	var upload Upload
+	if err := json.NewDecoder(resp.Body).Decode(&upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func writeUploadForm(mw *multipart.Writer, file io.Reader, params UploadParams) error {
+	fields := []struct{ name, value string }{
+		{"data_type", params.DataType},
+		{"name", params.Name},
+		{"description", params.Description},
+		{"external_id", params.ExternalID},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if err := mw.WriteField(f.name, f.value); err != nil {
+			return err
+		}
+	}
+	if params.Trainer {
+		if err := mw.WriteField("trainer", "1"); err != nil {
+			return err
+		}
+	}
+	if params.Commute {
+		if err := mw.WriteField("commute", "1"); err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile("file", "activity."+params.DataType)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+// WaitForUpload polls an upload until Strava has turned it into an
+// activity, backing off between checks. It returns the final Upload, whose
+// ActivityID is set, or an error wrapping ErrUploadFailed if Strava
+// rejected the file.
+func (c *Client) WaitForUpload(ctx context.Context, uploadID int64) (*Upload, error) {
+	delay := uploadPollMin
+	for {
+		upload, err := c.GetUploadCtx(ctx, uploadID)
+		if err != nil {
+			return nil, err
+		}
+		if upload.Error != "" {
+			return upload, fmt.Errorf("%w: %s", ErrUploadFailed, upload.Error)
+		}
+		if upload.ActivityID != 0 {
+			return upload, nil
+		}
+		if err := sleepCtx(ctx, delay); err != nil {
+			return upload, err
+		}
+		if delay *= 2; delay > uploadPollMax {
+			delay = uploadPollMax
+		}
+	}
+}
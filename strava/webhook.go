@@ -0,0 +1,250 @@
+package strava
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Webhook object and aspect types.
+const (
+	WebhookObjectActivity = "activity"
+	WebhookObjectAthlete  = "athlete"
+
+	WebhookAspectCreate = "create"
+	WebhookAspectUpdate = "update"
+	WebhookAspectDelete = "delete"
+)
+
+// maxWebhookBody bounds the size of an event body.
+const maxWebhookBody = 64 << 10
+
+// WebhookEvent is a push event delivered to a subscription callback.
+type WebhookEvent struct {
+	// ObjectType is WebhookObjectActivity or WebhookObjectAthlete.
+	ObjectType string `json:"object_type"`
+	// ObjectID is the activity or athlete ID.
+	ObjectID int64 `json:"object_id"`
+	// AspectType is WebhookAspectCreate, WebhookAspectUpdate or
+	// WebhookAspectDelete.
+	AspectType string `json:"aspect_type"`
+	// Updates holds the changed fields of an update, e.g. "title", "type"
+	// or "private".
+	Updates WebhookUpdates `json:"updates"`
+	// OwnerID is the athlete who owns the object.
+	OwnerID        int64 `json:"owner_id"`
+	SubscriptionID int64 `json:"subscription_id"`
+	// EventTime is in seconds since the epoch.
+	EventTime int64 `json:"event_time"`
+}
+
+// DeauthorizationEvent reports that an athlete revoked the application's
+// access. Their tokens no longer work and should be deleted.
+type DeauthorizationEvent struct {
+	AthleteID      int64
+	SubscriptionID int64
+	EventTime      int64
+}
+
+// Deauthorization reports whether e is an athlete deauthorization, which
+// Strava sends as an athlete update with "authorized" set to "false".
+func (e WebhookEvent) Deauthorization() (DeauthorizationEvent, bool) {
+	if e.ObjectType != WebhookObjectAthlete || e.AspectType != WebhookAspectUpdate || e.Updates["authorized"] != "false" {
+		return DeauthorizationEvent{}, false
+	}
+	return DeauthorizationEvent{
+		AthleteID:      e.ObjectID,
+		SubscriptionID: e.SubscriptionID,
+		EventTime:      e.EventTime,
+	}, true
+}
+
+// Time returns EventTime as a time.Time.
+func (e WebhookEvent) Time() time.Time {
+	return time.Unix(e.EventTime, 0)
+}
+
+// WebhookUpdates maps changed field names to their new values. Values are
+// documented as strings; other JSON scalars are converted to their string
+// form so a stray boolean does not fail the whole event.
+type WebhookUpdates map[string]string
+
+func (u *WebhookUpdates) UnmarshalJSON(data []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*u = nil
+		return nil
+	}
+	updates := make(WebhookUpdates, len(raw))
+	for k, v := range raw {
+		switch v := v.(type) {
+		case string:
+			updates[k] = v
+		case nil:
+			updates[k] = ""
+		default:
+			updates[k] = fmt.Sprint(v)
+		}
+	}
+	*u = updates
+	return nil
+}
+
+// WebhookHandler is the http.Handler for a subscription callback URL. It
+// answers Strava's hub.challenge verification and acknowledges events
+// immediately, then hands them to the registered callbacks and channel in
+// the background so slow processing never misses Strava's two-second
+// deadline. Callbacks may run concurrently and in any order; a panicking
+// callback is recovered and logged.
+type WebhookHandler struct {
+	// VerifyToken must match the verify token given to CreateSubscription.
+	VerifyToken string
+	// Events, if set, receives every event. Delivery blocks until the
+	// event is received or the handler is closed, so keep the channel
+	// drained.
+	Events chan<- WebhookEvent
+	// ErrorLog receives recovered callback panics. Nil means the log
+	// package's standard logger.
+	ErrorLog *log.Logger
+
+	mu            sync.Mutex
+	callbacks     []func(WebhookEvent)
+	deauthorizers []func(DeauthorizationEvent)
+	inflight      sync.WaitGroup
+	closed        bool
+	stop          chan struct{}
+}
+
+func NewWebhookHandler(verifyToken string) *WebhookHandler {
+	return &WebhookHandler{VerifyToken: verifyToken}
+}
+
+// OnEvent registers fn to be called for every event.
+func (h *WebhookHandler) OnEvent(fn func(WebhookEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.callbacks = append(h.callbacks, fn)
+}
+
+// OnDeauthorize registers fn to be called when an athlete deauthorizes the
+// application. Deauthorization events are also passed to OnEvent callbacks
+// and the Events channel.
+func (h *WebhookHandler) OnDeauthorize(fn func(DeauthorizationEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deauthorizers = append(h.deauthorizers, fn)
+}
+
+// Close stops the handler: later events are refused with 503 Service
+// Unavailable, and pending sends on Events are abandoned. Callbacks that
+// are already running are not interrupted.
+func (h *WebhookHandler) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.closed {
+		h.closed = true
+		close(h.stopChan())
+	}
+}
+
+// Wait blocks until the events accepted so far have been dispatched. Call
+// it only once no more events can arrive, that is after Close or after the
+// HTTP server has shut down.
+func (h *WebhookHandler) Wait() {
+	h.inflight.Wait()
+}
+
+// stopChan returns the channel closed by Close. The caller holds h.mu.
+func (h *WebhookHandler) stopChan() chan struct{} {
+	if h.stop == nil {
+		h.stop = make(chan struct{})
+	}
+	return h.stop
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		h.verify(w, r)
+	case "POST":
+		h.receive(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify answers the subscription challenge.
+func (h *WebhookHandler) verify(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("hub.mode") != "subscribe" || q.Get("hub.verify_token") != h.VerifyToken {
+		http.Error(w, "invalid verification request", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"hub.challenge": q.Get("hub.challenge")})
+}
+
+func (h *WebhookHandler) receive(w http.ResponseWriter, r *http.Request) {
+	var ev WebhookEvent
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxWebhookBody)).Decode(&ev); err != nil {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		http.Error(w, "webhook handler closed", http.StatusServiceUnavailable)
+		return
+	}
+	callbacks := append(([]func(WebhookEvent))(nil), h.callbacks...)
+	deauthorizers := append(([]func(DeauthorizationEvent))(nil), h.deauthorizers...)
+	stop := h.stopChan()
+	// Adding under h.mu, after the closed check, keeps Add from racing
+	// with a Wait that follows Close.
+	h.inflight.Add(1)
+	h.mu.Unlock()
+	go h.dispatch(ev, callbacks, deauthorizers, stop)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *WebhookHandler) dispatch(ev WebhookEvent, callbacks []func(WebhookEvent), deauthorizers []func(DeauthorizationEvent), stop <-chan struct{}) {
+	defer h.inflight.Done()
+	if deauth, ok := ev.Deauthorization(); ok {
+		for _, fn := range deauthorizers {
+			h.call(func() { fn(deauth) })
+		}
+	}
+	for _, fn := range callbacks {
+		h.call(func() { fn(ev) })
+	}
+	if h.Events != nil {
+		select {
+		case h.Events <- ev:
+		case <-stop:
+		}
+	}
+}
+
+// call runs fn, recovering and logging a panic so one bad callback cannot
+// take down the process or skip the others.
+func (h *WebhookHandler) call(fn func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			logf := log.Printf
+			if h.ErrorLog != nil {
+				logf = h.ErrorLog.Printf
+			}
+			logf("strava: panic in webhook callback: %v\n%s", err, debug.Stack())
+		}
+	}()
+	fn()
+}
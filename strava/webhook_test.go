@@ -0,0 +1,136 @@
+package strava_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yrludev/strava-golang-api-wrapper/strava"
+	"github.com/yrludev/strava-golang-api-wrapper/strava/stravatest"
+)
+
+const testVerifyToken = "verify-me"
+
+// subscribe serves hook over HTTP and subscribes it to a fresh stravatest
+// server, which checks the hub.challenge echo on the way.
+func subscribe(t *testing.T, hook *strava.WebhookHandler) (*stravatest.Server, *httptest.Server) {
+	t.Helper()
+	srv := stravatest.NewServer(nil)
+	t.Cleanup(srv.Close)
+	callback := httptest.NewServer(hook)
+	t.Cleanup(callback.Close)
+	subs := strava.NewSubscriptionClient("1", "secret", strava.WithBaseURL(srv.BaseURL()))
+	if _, err := subs.CreateSubscription(callback.URL, testVerifyToken); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	return srv, callback
+}
+
+var activityCreated = strava.WebhookEvent{
+	ObjectType: strava.WebhookObjectActivity,
+	ObjectID:   1,
+	AspectType: strava.WebhookAspectCreate,
+	OwnerID:    2,
+}
+
+// waitReturns fails t if fn does not return within a second.
+func waitReturns(t *testing.T, name string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("%s did not return", name)
+	}
+}
+
+func TestWebhookVerification(t *testing.T) {
+	hook := strava.NewWebhookHandler(testVerifyToken)
+	_, callback := subscribe(t, hook)
+
+	resp, err := http.Get(callback.URL + "?hub.mode=subscribe&hub.challenge=abc&hub.verify_token=wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("wrong verify token answered %s, want 403", resp.Status)
+	}
+
+	srv := stravatest.NewServer(nil)
+	defer srv.Close()
+	subs := strava.NewSubscriptionClient("1", "secret", strava.WithBaseURL(srv.BaseURL()))
+	if _, err := subs.CreateSubscription(callback.URL, "wrong"); err == nil {
+		t.Error("CreateSubscription with the wrong verify token succeeded")
+	}
+}
+
+func TestWebhookPanickingCallback(t *testing.T) {
+	var logs bytes.Buffer
+	hook := strava.NewWebhookHandler(testVerifyToken)
+	hook.ErrorLog = log.New(&logs, "", 0)
+	got := make(chan strava.WebhookEvent, 1)
+	hook.OnEvent(func(strava.WebhookEvent) { panic("boom") })
+	hook.OnEvent(func(ev strava.WebhookEvent) { got <- ev })
+	srv, _ := subscribe(t, hook)
+
+	if err := srv.PushEvent(activityCreated); err != nil {
+		t.Fatalf("PushEvent: %v", err)
+	}
+	select {
+	case ev := <-got:
+		if ev.ObjectID != activityCreated.ObjectID {
+			t.Errorf("ObjectID = %d, want %d", ev.ObjectID, activityCreated.ObjectID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second callback did not run after the first panicked")
+	}
+	waitReturns(t, "Wait", hook.Wait)
+	if !strings.Contains(logs.String(), "boom") {
+		t.Errorf("panic was not logged, got %q", logs.String())
+	}
+}
+
+func TestWebhookCloseUnblocksEvents(t *testing.T) {
+	hook := strava.NewWebhookHandler(testVerifyToken)
+	var mu sync.Mutex
+	dispatched := false
+	hook.OnEvent(func(strava.WebhookEvent) {
+		mu.Lock()
+		dispatched = true
+		mu.Unlock()
+	})
+	hook.Events = make(chan strava.WebhookEvent) // never drained
+	srv, _ := subscribe(t, hook)
+
+	if err := srv.PushEvent(activityCreated); err != nil {
+		t.Fatalf("PushEvent: %v", err)
+	}
+	hook.Close()
+	waitReturns(t, "Wait", hook.Wait)
+	mu.Lock()
+	defer mu.Unlock()
+	if !dispatched {
+		t.Error("callback did not run before Wait returned")
+	}
+}
+
+func TestWebhookClosed(t *testing.T) {
+	hook := strava.NewWebhookHandler(testVerifyToken)
+	srv, _ := subscribe(t, hook)
+	hook.Close()
+
+	err := srv.PushEvent(activityCreated)
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("PushEvent after Close error = %v, want a 503", err)
+	}
+}